@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/config"
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/importer"
+	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+)
+
+func main() {
+	configPath := flag.String("config", "config.json", "Path to config file")
+	importType := flag.String("type", "", "What the file holds: users or orders")
+	format := flag.String("format", "", "File format: csv or ndjson, detected from the file extension when empty")
+	flag.Parse()
+
+	if len(flag.Args()) != 1 {
+		log.Fatal("Usage: import -type users|orders [-config config.json] [-format csv|ndjson] FILE (- for stdin)")
+	}
+	path := flag.Arg(0)
+	if *importType != api.ImportTypeUsers && *importType != api.ImportTypeOrders {
+		log.Fatalf("Unknown type: %q. Valid types: users, orders", *importType)
+	}
+	if *format == "" {
+		f, ok := importer.FormatFromPath(path)
+		if !ok {
+			log.Fatalf("Cannot detect the format of %s, set -format", path)
+		}
+		*format = string(f)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Error opening file: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	rows, err := importer.NewReader(input, importer.Format(*format))
+	if err != nil {
+		log.Fatalf("Error reading file: %v", err)
+	}
+
+	db, err := config.InitDB(cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting DB: %v", err)
+	}
+	redisClient := config.InitRedis(cfg.Redis)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Imported rows invalidate the caches of the running servers, so the
+	// cache is set up the same way as theirs.
+	cacheManager, err := config.InitCache(ctx, cfg, redisClient, adapter.NewCircuitBreaker(adapter.BreakerOptions{
+		FailureThreshold: cfg.Cache.Breaker.FailureThreshold,
+		OpenTimeout:      time.Duration(cfg.Cache.Breaker.OpenTimeout) * time.Second,
+		MaxPending:       cfg.Cache.Breaker.MaxPending,
+	}))
+	if err != nil {
+		log.Fatalf("Error setting up cache: %v", err)
+	}
+
+	userRepo := adapter.NewUserRepository(db)
+	orderRepo := adapter.NewOrderRepository(db)
+	orderService := service.NewOrderService(orderRepo, userRepo, cacheManager, adapter.RefreshPolicy{
+		TTL: time.Duration(cfg.Redis.TTL) * time.Second,
+	})
+	importService := service.NewImportService(userRepo, orderService, cacheManager, cfg.Import.BatchSize)
+
+	// The command runs with the rights of an admin.
+	ctx = auth.WithCaller(ctx, auth.Caller{Role: entity.RoleAdmin})
+
+	var report service.ImportReport
+	if *importType == api.ImportTypeUsers {
+		report, err = importService.ImportUsers(ctx, rows)
+	} else {
+		report, err = importService.ImportOrders(ctx, rows)
+	}
+
+	for _, failure := range report.Failures {
+		_, resp := pkg.ErrorResponse(failure.Err, http.StatusBadRequest)
+		if resp.Data != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %s: %s %v\n", failure.Line, resp.Code, resp.Message, resp.Data)
+		} else {
+			fmt.Fprintf(os.Stderr, "line %d: %s: %s\n", failure.Line, resp.Code, resp.Message)
+		}
+	}
+	if report.Truncated() {
+		fmt.Fprintf(os.Stderr, "%d more failed rows not shown\n", report.Failed-len(report.Failures))
+	}
+	log.Printf("Read %d rows: %d imported, %d failed.\n", report.Rows, report.Imported, report.Failed)
+
+	if err != nil && report.StoppedAt > 0 {
+		log.Fatalf("Import stopped at line %d: %v", report.StoppedAt, err)
+	}
+	if err != nil {
+		log.Fatalf("Import stopped: %v", err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
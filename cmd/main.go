@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,64 +15,263 @@ import (
 
 	"github.com/farisarmap/dot-backend-freelance/config"
 	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
 	"github.com/farisarmap/dot-backend-freelance/internal/handler"
+	"github.com/farisarmap/dot-backend-freelance/internal/health"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/farisarmap/dot-backend-freelance/internal/metrics"
+	authmw "github.com/farisarmap/dot-backend-freelance/internal/middleware"
 	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/internal/tracing"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 func main() {
-	configPath := flag.String("config", "config.json", "Path to config file")
+	configPath := flag.String("config", "config.json", "Path to a JSON or YAML config file, empty to configure from the environment only")
+	printConfig := flag.Bool("print-config", false, "Print the effective config with secrets redacted and exit")
 	flag.Parse()
 
 	// Load config
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		fatal("loading config", err)
+	}
+	if *printConfig {
+		out, _ := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("invalid config", err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("loading config", err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		FilePath:    cfg.Tracing.FilePath,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("setting up tracing", err)
 	}
 
 	// Init DB
 	db, err := config.InitDB(cfg.Database)
 	if err != nil {
-		log.Fatalf("Error connecting DB: %v", err)
+		fatal("connecting DB", err)
+	}
+	slowQuery := time.Duration(cfg.Log.SlowQueryMS) * time.Millisecond
+	if cfg.Log.SlowQueryMS == 0 {
+		slowQuery = 200 * time.Millisecond
+	}
+	db.Logger = logging.NewGormLogger(slowQuery)
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		fatal("registering DB tracing", err)
+	}
+
+	appMetrics := metrics.New()
+	if err := db.Use(appMetrics.GormPlugin()); err != nil {
+		fatal("registering DB metrics", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("getting sql.DB from GORM", err)
 	}
+	appMetrics.RegisterDBStats(sqlDB, cfg.Database.DBName)
+
+	accessTTL := time.Duration(cfg.Auth.AccessTokenTTLMinutes) * time.Minute
+	refreshTTL := time.Duration(cfg.Auth.RefreshTokenTTLHours) * time.Hour
+	tokenManager := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.Issuer, accessTTL, refreshTTL)
 
 	// Init Redis
 	redisClient := config.InitRedis(cfg.Redis)
+	if err := redisotel.InstrumentTracing(redisClient); err != nil {
+		fatal("registering Redis tracing", err)
+	}
 
 	userRepo := adapter.NewUserRepository(db)
 	orderRepo := adapter.NewOrderRepository(db)
+	searchRepo := adapter.NewSearchRepository(db)
+	idempotencyRepo := adapter.NewIdempotencyRepository(db)
+	exportJobRepo := adapter.NewExportJobRepository(db)
+	refreshTokenRepo := adapter.NewRefreshTokenRepository(db)
+
+	cacheCtx, stopCache := context.WithCancel(context.Background())
+	defer stopCache()
+
+	cacheBreaker := adapter.NewCircuitBreaker(adapter.BreakerOptions{
+		FailureThreshold: cfg.Cache.Breaker.FailureThreshold,
+		OpenTimeout:      time.Duration(cfg.Cache.Breaker.OpenTimeout) * time.Second,
+		MaxPending:       cfg.Cache.Breaker.MaxPending,
+	})
+
+	cacheManager, err := config.InitCache(cacheCtx, cfg, redisClient, cacheBreaker)
+	if err != nil {
+		fatal("loading config", err)
+	}
+	cacheManager = adapter.ObserveCache(cacheManager, appMetrics)
 
-	cacheManager := adapter.NewRedisCache(redisClient, time.Duration(cfg.Redis.TTL)*time.Second)
+	listRefresh := adapter.RefreshPolicy{
+		TTL:      time.Duration(cfg.Redis.TTL) * time.Second,
+		StaleTTL: time.Duration(cfg.Cache.StaleTTL) * time.Second,
+		Beta:     cfg.Cache.EarlyRefreshBeta,
+	}
+
+	userService := service.TraceUserService(service.NewUserService(userRepo, orderRepo, cacheManager, listRefresh))
+	orderService := service.TraceOrderService(service.NewOrderService(orderRepo, userRepo, cacheManager, listRefresh))
+	searchService := service.NewSearchService(searchRepo)
+	importService := service.NewImportService(userRepo, orderService, cacheManager, cfg.Import.BatchSize)
+	exportService := service.NewExportService(orderRepo, exportJobRepo, service.ExportOptions{
+		Dir:         cfg.Export.Dir,
+		MaxSyncRows: int64(cfg.Export.MaxSyncRows),
+		BatchSize:   cfg.Export.BatchSize,
+		TTL:         time.Duration(cfg.Export.TTLHours) * time.Hour,
+		JobTimeout:  time.Duration(cfg.Export.JobTimeout) * time.Second,
+	})
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, cacheManager, tokenManager)
 
-	userService := service.NewUserService(userRepo, orderRepo, cacheManager)
-	orderService := service.NewOrderService(orderRepo, userRepo, cacheManager)
+	if cfg.Auth.AdminEmail != "" {
+		if err := authService.EnsureAdmin(context.Background(), "Administrator", cfg.Auth.AdminEmail, cfg.Auth.AdminPassword); err != nil {
+			fatal("creating admin user", err)
+		}
+	}
+	purgeService := service.NewPurgeService(userRepo, orderRepo, idempotencyRepo, refreshTokenRepo, time.Duration(cfg.SoftDelete.RetentionDays)*24*time.Hour)
 
 	userHandler := handler.NewUserHandler(userService)
-	orderHandler := handler.NewOrderHandler(orderService, cacheManager)
+	orderHandler := handler.NewOrderHandler(orderService, cacheManager, cfg.Server.RequireIfMatch)
+	searchHandler := handler.NewSearchHandler(searchService)
+	importHandler := handler.NewImportHandler(importService)
+	exportHandler := handler.NewExportHandler(exportService)
+	authHandler := handler.NewAuthHandler(authService)
+	readiness, err := newReadinessChecker(cfg, sqlDB)
+	if err != nil {
+		fatal("loading migrations", err)
+	}
+	healthHandler := handler.NewHealthHandler(cacheBreaker, readiness, newCacheChecker(cfg, redisClient))
 
 	e := echo.New()
+	e.HTTPErrorHandler = pkg.HTTPErrorHandler
+	for _, srv := range []*http.Server{e.Server, e.TLSServer} {
+		srv.ReadTimeout = time.Duration(cfg.Server.ReadTimeout) * time.Second
+		srv.WriteTimeout = time.Duration(cfg.Server.WriteTimeout) * time.Second
+		srv.IdleTimeout = time.Duration(cfg.Server.IdleTimeout) * time.Second
+	}
 
+	e.Use(otelecho.Middleware(cfg.Tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		switch c.Path() {
+		case "/metrics", "/healthz", "/readyz":
+			return true
+		}
+		return false
+	})))
+	e.Use(authmw.RequestLogger(logger))
+	e.Use(appMetrics.Middleware())
 	e.Use(middleware.Recover())
+	if cfg.Server.BodyLimit != "" {
+		// Imports have their own, larger limit.
+		e.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+			Limit:   cfg.Server.BodyLimit,
+			Skipper: func(c echo.Context) bool { return c.Path() == "/imports" },
+		}))
+	}
+	e.Use(authmw.TimeoutWithConfig(authmw.TimeoutConfig{
+		Timeout: time.Duration(cfg.Server.HandlerTimeout) * time.Second,
+		// Imports have their own, longer limit.
+		Skipper: func(c echo.Context) bool { return c.Path() == "/imports" },
+	}))
+
+	e.GET("/metrics", echo.WrapHandler(appMetrics.Handler()))
+
+	e.GET("/healthz", healthHandler.Live)
+	e.GET("/readyz", healthHandler.Ready)
+	e.GET("/health/cache", healthHandler.Cache)
+
+	e.POST("/auth/register", authHandler.Register)
+	e.POST("/auth/login", authHandler.Login)
+	e.POST("/auth/refresh", authHandler.Refresh)
+	e.POST("/auth/logout", authHandler.Logout)
+
+	protected := e.Group("", authmw.JWTAuth(tokenManager))
+	// Bulk writes carry their versions in the body, so If-Match is only
+	// required on the routes of a single user or order; the order handler
+	// requires the versions of bulk items instead.
+	var conditional []echo.MiddlewareFunc
+	if cfg.Server.RequireIfMatch {
+		conditional = append(conditional, authmw.RequireIfMatch())
+	}
+	idempotent := authmw.Idempotency(idempotencyRepo, authmw.IdempotencyOptions{
+		TTL:         time.Duration(cfg.Idempotency.TTLHours) * time.Hour,
+		LockTimeout: time.Duration(cfg.Idempotency.LockTimeout) * time.Second,
+	})
 
-	e.GET("/users", userHandler.GetAllUsers)
-	e.POST("/users", userHandler.CreateUser)
-	e.GET("/users/:id", userHandler.GetUserByID)
-	e.PUT("/users/:id", userHandler.UpdateUser)
-	e.PATCH("/users/:id", userHandler.PartialUpdateUser)
-	e.DELETE("/users/:id", userHandler.DeleteUser)
-
-	e.POST("/users-and-orders", orderHandler.CreateUserAndOrder)
-	e.GET("/orders", orderHandler.GetAllOrders)
-	e.POST("/orders", orderHandler.CreateOrder)
-	e.GET("/orders/:id", orderHandler.GetOrderByID)
-	e.PUT("/orders/:id", orderHandler.UpdateOrder)
-	e.PATCH("/orders/:id", orderHandler.PartialUpdateOrder)
-	e.DELETE("/orders/:id", orderHandler.DeleteOrder)
+	protected.GET("/users", userHandler.GetAllUsers)
+	protected.POST("/users", userHandler.CreateUser, idempotent)
+	protected.GET("/users/:id", userHandler.GetUserByID)
+	protected.PUT("/users/:id", userHandler.UpdateUser, conditional...)
+	protected.PATCH("/users/:id", userHandler.PartialUpdateUser, conditional...)
+	protected.DELETE("/users/:id", userHandler.DeleteUser, conditional...)
+	protected.POST("/users/:id/restore", userHandler.RestoreUser)
+
+	protected.POST("/users-and-orders", orderHandler.CreateUserAndOrder, idempotent)
+	protected.GET("/orders", orderHandler.GetAllOrders)
+	protected.GET("/me/orders", orderHandler.GetMyOrders)
+	protected.POST("/orders", orderHandler.CreateOrder, idempotent)
+	protected.POST("/orders/bulk", orderHandler.BulkCreateOrders, idempotent)
+	protected.GET("/orders/export", exportHandler.ExportOrders)
+	protected.POST("/orders/exports", exportHandler.CreateExportJob, idempotent)
+	protected.GET("/orders/exports/:id", exportHandler.GetExportJob)
+	protected.GET("/orders/exports/:id/download", exportHandler.DownloadExport)
+	protected.PATCH("/orders/bulk", orderHandler.BulkUpdateOrders)
+	protected.DELETE("/orders/bulk", orderHandler.BulkDeleteOrders)
+	protected.GET("/orders/:id", orderHandler.GetOrderByID)
+	protected.PUT("/orders/:id", orderHandler.UpdateOrder, conditional...)
+	protected.PATCH("/orders/:id", orderHandler.PartialUpdateOrder, conditional...)
+	protected.DELETE("/orders/:id", orderHandler.DeleteOrder, conditional...)
+	protected.POST("/orders/:id/restore", orderHandler.RestoreOrder)
+	protected.GET("/orders/:id/transitions", orderHandler.GetOrderStatusHistory)
+	protected.POST("/orders/:id/transitions", orderHandler.TransitionOrder, idempotent)
+
+	protected.GET("/search", searchHandler.Search)
+
+	importTimeout := time.Duration(cfg.Import.Timeout) * time.Second
+	importMiddleware := []echo.MiddlewareFunc{authmw.ConnDeadline(importTimeout), authmw.Timeout(importTimeout)}
+	if cfg.Import.BodyLimit != "" {
+		importMiddleware = append(importMiddleware, middleware.BodyLimit(cfg.Import.BodyLimit))
+	}
+	protected.POST("/imports", importHandler.Import, importMiddleware...)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	purgeInterval := time.Duration(cfg.SoftDelete.PurgeIntervalMinutes) * time.Minute
+	if purgeInterval <= 0 {
+		purgeInterval = time.Hour
+	}
+	go purgeService.Run(backgroundCtx, purgeInterval)
+	go exportService.Run(backgroundCtx, time.Duration(cfg.Export.PollInterval)*time.Second)
 
 	go func() {
-		if err := e.Start(":8080"); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("error starting server: %v", err)
+		var err error
+		if cfg.Server.TLSCertFile != "" {
+			err = e.StartTLS(cfg.Server.Address, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			err = e.Start(cfg.Server.Address)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("starting server", err)
 		}
 	}()
 
@@ -77,10 +279,52 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Fail readiness first so that load balancers stop routing new requests
+	// here before the listener closes.
+	readiness.Drain()
+	time.Sleep(time.Duration(cfg.Health.DrainSeconds) * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	if err := e.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("server forced to shutdown", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("flushing traces", "error", err)
+	}
+}
+
+// newReadinessChecker checks the dependencies the API cannot serve without.
+// Redis is not one of them, since the cache is bypassed while it is down.
+func newReadinessChecker(cfg *config.Config, sqlDB *sql.DB) (*health.Checker, error) {
+	dir := cfg.Health.MigrationDir
+	if dir == "" {
+		dir = "migration"
+	}
+	migrations, err := health.Migrations(sqlDB, dir)
+	if err != nil {
+		return nil, err
 	}
+
+	checker := health.NewChecker(time.Duration(cfg.Health.CheckTimeout) * time.Second)
+	checker.Add("postgres", health.Postgres(sqlDB))
+	checker.Add("migrations", migrations)
+	return checker, nil
+}
+
+// newCacheChecker checks Redis for /health/cache, unless the cache is kept
+// in memory.
+func newCacheChecker(cfg *config.Config, redisClient *redis.Client) *health.Checker {
+	checker := health.NewChecker(time.Duration(cfg.Health.CheckTimeout) * time.Second)
+	if cfg.Cache.Mode != config.CacheModeMemory {
+		checker.Add("redis", health.Redis(redisClient))
+	}
+	return checker
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
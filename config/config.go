@@ -1,68 +1,350 @@
 package config
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// Config is the application configuration. LoadConfig builds it from the
+// defaults, a config file and the environment; fields tagged secret are
+// redacted by Redacted.
 type Config struct {
-	Database DatabaseConfig `json:"database"`
-	Redis    RedisConfig    `json:"redis"`
+	Server      ServerConfig      `json:"server"`
+	Database    DatabaseConfig    `json:"database"`
+	Redis       RedisConfig       `json:"redis"`
+	SoftDelete  SoftDeleteConfig  `json:"soft_delete"`
+	Auth        AuthConfig        `json:"auth"`
+	Cache       CacheConfig       `json:"cache"`
+	Health      HealthConfig      `json:"health"`
+	Tracing     TracingConfig     `json:"tracing"`
+	Log         LogConfig         `json:"log"`
+	Idempotency IdempotencyConfig `json:"idempotency"`
+	Import      ImportConfig      `json:"import"`
+	Export      ExportConfig      `json:"export"`
 }
 
+// ServerConfig configures the HTTP server. Timeouts are in seconds. Zero
+// read, write, idle and handler timeouts disable them. Each handler gets
+// HandlerTimeout seconds (default 120) and in-flight requests get
+// ShutdownTimeout seconds (default 5) to finish on shutdown. BodyLimit caps
+// request bodies, e.g. "10M"; empty is unlimited. The server uses TLS when
+// TLSCertFile and TLSKeyFile are set. RequireIfMatch rejects PUT, PATCH and
+// DELETE requests without an If-Match header, and bulk order items without
+// a version.
+type ServerConfig struct {
+	Address         string `json:"address"`
+	ReadTimeout     int    `json:"read_timeout"`
+	WriteTimeout    int    `json:"write_timeout"`
+	IdleTimeout     int    `json:"idle_timeout"`
+	HandlerTimeout  int    `json:"handler_timeout"`
+	ShutdownTimeout int    `json:"shutdown_timeout"`
+	BodyLimit       string `json:"body_limit"`
+	TLSCertFile     string `json:"tls_cert_file"`
+	TLSKeyFile      string `json:"tls_key_file"`
+	RequireIfMatch  bool   `json:"require_if_match"`
+}
+
+// DatabaseConfig configures the Postgres connection and its pool. A zero
+// MaxOpenConns is unlimited, but a zero MaxIdleConns keeps no idle
+// connection, so every query opens a new one. ConnMaxLifetime is in seconds;
+// zero keeps connections forever. StatementTimeoutMS aborts statements
+// running longer than that many milliseconds; zero leaves the server
+// default.
 type DatabaseConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
-	DBName   string `json:"dbname"`
+	Host               string `json:"host"`
+	Port               int    `json:"port"`
+	User               string `json:"user"`
+	Password           string `json:"password" secret:"true"`
+	DBName             string `json:"dbname"`
+	SSLMode            string `json:"sslmode"`
+	MaxOpenConns       int    `json:"max_open_conns"`
+	MaxIdleConns       int    `json:"max_idle_conns"`
+	ConnMaxLifetime    int    `json:"conn_max_lifetime"`
+	StatementTimeoutMS int    `json:"statement_timeout_ms"`
+	ApplicationName    string `json:"application_name"`
 }
 
+// RedisConfig configures the Redis client. Timeouts are in seconds; zero
+// values for PoolSize and the timeouts use the client defaults.
 type RedisConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Password string `json:"password"`
-	TTL      int    `json:"ttl"`
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	Password     string `json:"password" secret:"true"`
+	TTL          int    `json:"ttl"`
+	DB           int    `json:"db"`
+	PoolSize     int    `json:"pool_size"`
+	TLS          bool   `json:"tls"`
+	DialTimeout  int    `json:"dial_timeout"`
+	ReadTimeout  int    `json:"read_timeout"`
+	WriteTimeout int    `json:"write_timeout"`
 }
 
-func LoadConfig(configPath string) (*Config, error) {
-	file, err := os.Open(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
-	}
-	defer file.Close()
+// SoftDeleteConfig controls how long soft deleted rows are kept before the
+// scheduled purge removes them. A zero RetentionDays disables the purge.
+type SoftDeleteConfig struct {
+	RetentionDays        int `json:"retention_days"`
+	PurgeIntervalMinutes int `json:"purge_interval_minutes"`
+}
 
-	var cfg Config
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to decode config file: %w", err)
-	}
+const (
+	CacheModeRedis   = "redis"
+	CacheModeMemory  = "memory"
+	CacheModeLayered = "layered"
+)
+
+const (
+	CacheCodecJSON    = "json"
+	CacheCodecMsgpack = "msgpack"
+)
+
+// CacheConfig selects the cache store. Mode is "redis" (the default),
+// "memory" for an in-process LRU cache, or "layered" for an in-process cache
+// in front of Redis. The in-process cache keeps at most MaxEntries entries
+// and MaxBytes bytes, each for LocalTTL seconds; zero limits are unlimited
+// and a zero LocalTTL falls back to redis.ttl. Codec is "json" (the
+// default) or "msgpack".
+//
+// List pages are refreshed early with probability controlled by
+// EarlyRefreshBeta (0 disables it, 1 is a good default) and, when StaleTTL is
+// positive, served up to StaleTTL seconds past expiry while one request
+// refreshes them.
+//
+// In the redis and layered modes, cache keys are stored under KeyPrefix
+// (default "cache:"), so that the cache can be cleared without touching the
+// other keys of redis.db, and Breaker controls when Redis is bypassed after
+// connection errors.
+type CacheConfig struct {
+	Mode                string        `json:"mode"`
+	Codec               string        `json:"codec"`
+	MaxEntries          int           `json:"max_entries"`
+	MaxBytes            int64         `json:"max_bytes"`
+	LocalTTL            int           `json:"local_ttl"`
+	KeyPrefix           string        `json:"key_prefix"`
+	InvalidationChannel string        `json:"invalidation_channel"`
+	StaleTTL            int           `json:"stale_ttl"`
+	EarlyRefreshBeta    float64       `json:"early_refresh_beta"`
+	Breaker             BreakerConfig `json:"breaker"`
+}
+
+// BreakerConfig configures the Redis circuit breaker. After FailureThreshold
+// consecutive connection errors Redis is bypassed for OpenTimeout seconds;
+// at most MaxPending invalidations are kept to replay once it is back. Zero
+// values use the defaults.
+type BreakerConfig struct {
+	FailureThreshold int `json:"failure_threshold"`
+	OpenTimeout      int `json:"open_timeout"`
+	MaxPending       int `json:"max_pending"`
+}
+
+// HealthConfig configures /readyz and /health/cache. MigrationDir holds the
+// migrations the schema must be up to date with (default "migration"). Each
+// dependency check gets CheckTimeout seconds (default 2). On shutdown, /readyz fails for
+// DrainSeconds before the server stops accepting requests.
+type HealthConfig struct {
+	MigrationDir string `json:"migration_dir"`
+	CheckTimeout int    `json:"check_timeout"`
+	DrainSeconds int    `json:"drain_seconds"`
+}
+
+// TracingConfig configures OpenTelemetry tracing. Exporter is "none" (the
+// default), "stdout" or "file" to write spans as JSON to stdout or FilePath,
+// or "otlp" to send them over OTLP/HTTP to Endpoint (host:port, by default
+// localhost:4318); Insecure disables TLS. SampleRatio is the fraction of new
+// traces recorded (default 1); requests carrying a traceparent header follow
+// the caller's decision.
+type TracingConfig struct {
+	ServiceName string  `json:"service_name"`
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"`
+	Insecure    bool    `json:"insecure"`
+	FilePath    string  `json:"file_path"`
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// LogConfig configures the structured logger. Level is "debug", "info" (the
+// default), "warn" or "error" and Format is "json" (the default) or "text".
+// Queries slower than SlowQueryMS milliseconds are logged as warnings; zero
+// uses 200 and a negative value disables slow query logging.
+type LogConfig struct {
+	Level       string `json:"level"`
+	Format      string `json:"format"`
+	SlowQueryMS int    `json:"slow_query_ms"`
+}
+
+// IdempotencyConfig configures Idempotency-Key handling. Responses are
+// replayed for TTLHours hours (default 24). A request that has not completed
+// after LockTimeout seconds (default 180, must exceed server.handler_timeout)
+// is assumed lost and its key may be reused.
+type IdempotencyConfig struct {
+	TTLHours    int `json:"ttl_hours"`
+	LockTimeout int `json:"lock_timeout"`
+}
+
+// ImportConfig configures POST /imports and the import command. Rows are
+// written in batches of BatchSize (default 500), each in its own
+// transaction. BodyLimit caps an uploaded file (default "100M") and replaces
+// server.body_limit on that route. An upload gets Timeout seconds (default
+// 3600, zero disables it), which replaces the server's read, write and
+// handler timeouts on that route.
+type ImportConfig struct {
+	BatchSize int    `json:"batch_size"`
+	BodyLimit string `json:"body_limit"`
+	Timeout   int    `json:"timeout"`
+}
+
+// ExportConfig configures order exports. GET /orders/export refuses filters
+// matching more than MaxSyncRows orders (default 100000); larger exports go
+// through export jobs, whose files are written to Dir and kept for TTLHours
+// (default 24). Rows are read from the database BatchSize (default 1000) at
+// a time. Workers look for pending jobs every PollInterval seconds (default
+// 5) and a job running longer than JobTimeout seconds (default 3600) is
+// taken over by another worker. Dir must be shared by all instances.
+type ExportConfig struct {
+	Dir          string `json:"dir"`
+	MaxSyncRows  int    `json:"max_sync_rows"`
+	BatchSize    int    `json:"batch_size"`
+	TTLHours     int    `json:"ttl_hours"`
+	PollInterval int    `json:"poll_interval"`
+	JobTimeout   int    `json:"job_timeout"`
+}
 
-	return &cfg, nil
+// AuthConfig holds the JWT signing settings. JWTSecret is required and must
+// be at least 32 characters. When AdminEmail is set, an admin account with
+// these credentials is created on startup if no user has that email yet.
+// Placeholder values such as "change-me" are rejected for both secrets.
+type AuthConfig struct {
+	JWTSecret             string `json:"jwt_secret" secret:"true"`
+	Issuer                string `json:"issuer"`
+	AccessTokenTTLMinutes int    `json:"access_token_ttl_minutes"`
+	RefreshTokenTTLHours  int    `json:"refresh_token_ttl_hours"`
+	AdminEmail            string `json:"admin_email"`
+	AdminPassword         string `json:"admin_password" secret:"true"`
 }
 
 func InitDB(dbCfg DatabaseConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		dbCfg.Host, dbCfg.Port, dbCfg.User, dbCfg.Password, dbCfg.DBName,
-	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dbCfg.DSN()), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(dbCfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(dbCfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(dbCfg.ConnMaxLifetime) * time.Second)
 	return db, nil
 }
 
+// DSN returns the keyword/value connection string for the database. Empty
+// settings are left out.
+func (c DatabaseConfig) DSN() string {
+	params := [][2]string{
+		{"host", c.Host},
+		{"port", strconv.Itoa(c.Port)},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.DBName},
+		{"sslmode", c.SSLMode},
+		{"application_name", c.ApplicationName},
+	}
+	if c.StatementTimeoutMS > 0 {
+		params = append(params, [2]string{"statement_timeout", strconv.Itoa(c.StatementTimeoutMS)})
+	}
+
+	var b strings.Builder
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(p[0])
+		b.WriteByte('=')
+		b.WriteString(quoteDSNValue(p[1]))
+	}
+	return b.String()
+}
+
+// quoteDSNValue quotes v so that spaces, quotes and backslashes in passwords
+// and names survive parsing.
+func quoteDSNValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
 func InitRedis(rdCfg RedisConfig) *redis.Client {
-	addr := fmt.Sprintf("%s:%d", rdCfg.Host, rdCfg.Port)
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: rdCfg.Password,
-		DB:       0,
-	})
-	return rdb
+	opts := &redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", rdCfg.Host, rdCfg.Port),
+		Password:     rdCfg.Password,
+		DB:           rdCfg.DB,
+		PoolSize:     rdCfg.PoolSize,
+		DialTimeout:  time.Duration(rdCfg.DialTimeout) * time.Second,
+		ReadTimeout:  time.Duration(rdCfg.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(rdCfg.WriteTimeout) * time.Second,
+	}
+	if rdCfg.TLS {
+		opts.TLSConfig = &tls.Config{
+			ServerName: rdCfg.Host,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return redis.NewClient(opts)
+}
+
+// InitCache builds the cache manager selected by cache.mode. The layered
+// cache listens for invalidations from other instances until ctx is done.
+func InitCache(ctx context.Context, cfg *Config, redisClient *redis.Client, breaker *adapter.CircuitBreaker) (adapter.CacheManager, error) {
+	ttl := time.Duration(cfg.Redis.TTL) * time.Second
+
+	local := adapter.MemoryCacheOptions{
+		MaxEntries: cfg.Cache.MaxEntries,
+		MaxBytes:   cfg.Cache.MaxBytes,
+		TTL:        time.Duration(cfg.Cache.LocalTTL) * time.Second,
+	}
+	if local.TTL <= 0 {
+		local.TTL = ttl
+	}
+
+	var codec adapter.Codec
+	switch cfg.Cache.Codec {
+	case "", CacheCodecJSON:
+		codec = adapter.JSONCodec
+	case CacheCodecMsgpack:
+		codec = adapter.MsgpackCodec
+	default:
+		return nil, fmt.Errorf("unknown cache.codec %q", cfg.Cache.Codec)
+	}
+
+	prefix := cfg.Cache.KeyPrefix
+	if prefix == "" {
+		prefix = "cache:"
+	}
+
+	switch cfg.Cache.Mode {
+	case "", CacheModeRedis:
+		return adapter.NewRedisCache(redisClient, prefix, ttl, codec, breaker), nil
+	case CacheModeMemory:
+		return adapter.NewMemoryCache(local, codec), nil
+	case CacheModeLayered:
+		channel := cfg.Cache.InvalidationChannel
+		if channel == "" {
+			channel = "cache:invalidations"
+		}
+		return adapter.NewLayeredCache(ctx, redisClient, prefix, ttl, local, channel, codec, breaker), nil
+	default:
+		return nil, fmt.Errorf("unknown cache.mode %q", cfg.Cache.Mode)
+	}
 }
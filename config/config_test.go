@@ -0,0 +1,183 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  host: db.internal
+  user: app
+cache:
+  breaker:
+    failure_threshold: 3
+`)
+	secret := writeFile(t, "jwt", "from-file\n")
+	t.Setenv("APP_DATABASE_PORT", "6543")
+	t.Setenv("APP_CACHE_EARLY_REFRESH_BETA", "0.5")
+	t.Setenv("APP_TRACING_INSECURE", "true")
+	t.Setenv("APP_AUTH_JWT_SECRET_FILE", secret)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"file", cfg.Database.Host, "db.internal"},
+		{"nested file", cfg.Cache.Breaker.FailureThreshold, 3},
+		{"env", cfg.Database.Port, 6543},
+		{"env float", cfg.Cache.EarlyRefreshBeta, 0.5},
+		{"env bool", cfg.Tracing.Insecure, true},
+		{"secret file", cfg.Auth.JWTSecret, "from-file"},
+		{"default", cfg.Redis.Port, 6379},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigRejectsBadInput(t *testing.T) {
+	t.Run("unknown field", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"database": {"hostname": "x"}}`)
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "hostname") {
+			t.Fatalf("expected the unknown field to be rejected, got %v", err)
+		}
+	})
+	t.Run("invalid env value", func(t *testing.T) {
+		t.Setenv("APP_REDIS_PORT", "six")
+		if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "APP_REDIS_PORT") {
+			t.Fatalf("expected the invalid value to be rejected, got %v", err)
+		}
+	})
+	t.Run("value and file", func(t *testing.T) {
+		t.Setenv("APP_REDIS_PASSWORD", "x")
+		t.Setenv("APP_REDIS_PASSWORD_FILE", "/dev/null")
+		if _, err := LoadConfig(""); err == nil {
+			t.Fatal("expected setting both a value and a file to be rejected")
+		}
+	})
+}
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func TestValidate(t *testing.T) {
+	cfg := Defaults()
+	cfg.Cache.Mode = "disk"
+	cfg.Auth.AdminEmail = "admin@example.com"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"database.user: is required", "auth.jwt_secret: is required", "cache.mode", "auth.admin_password"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+
+	cfg = Defaults()
+	cfg.Database.User = "app"
+	cfg.Database.DBName = "app"
+	cfg.Auth.JWTSecret = testJWTSecret
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected the defaults plus required fields to be valid, got %v", err)
+	}
+}
+
+func TestValidateRejectsWeakSecrets(t *testing.T) {
+	cfg := Defaults()
+	cfg.Database.User = "app"
+	cfg.Database.DBName = "app"
+	cfg.Auth.JWTSecret = "Change-Me"
+	cfg.Auth.AdminEmail = "admin@example.com"
+	cfg.Auth.AdminPassword = "change-me-too"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"auth.jwt_secret: must be at least 32", `auth.jwt_secret: must not be the placeholder "change-me"`, `auth.admin_password: must not be the placeholder "change-me-too"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Defaults()
+	cfg.Database.Password = "postgres"
+	cfg.Auth.JWTSecret = "secret"
+
+	redacted := cfg.Redacted()
+	if redacted.Database.Password != "[REDACTED]" || redacted.Auth.JWTSecret != "[REDACTED]" {
+		t.Fatalf("expected secrets to be redacted, got %+v", redacted)
+	}
+	if redacted.Redis.Password != "" || redacted.Database.Host != "localhost" {
+		t.Fatalf("expected other fields to be kept, got %+v", redacted)
+	}
+	if cfg.Auth.JWTSecret != "secret" {
+		t.Fatal("expected the original config to be unchanged")
+	}
+}
+
+func TestDSN(t *testing.T) {
+	cfg := Defaults().Database
+	cfg.User = "app"
+	cfg.Password = `it's a \secret`
+	cfg.DBName = "orders"
+	cfg.StatementTimeoutMS = 5000
+
+	parsed, err := pgconn.ParseConfig(cfg.DSN())
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if parsed.Password != cfg.Password || parsed.Database != "orders" || parsed.Port != 5432 {
+		t.Fatalf("unexpected connection settings %+v", parsed)
+	}
+	if parsed.RuntimeParams["statement_timeout"] != "5000" || parsed.RuntimeParams["application_name"] != "dot-backend-freelance" {
+		t.Fatalf("unexpected runtime params %v", parsed.RuntimeParams)
+	}
+}
+
+func TestValidateServer(t *testing.T) {
+	cfg := Defaults()
+	cfg.Database.User = "app"
+	cfg.Database.DBName = "app"
+	cfg.Auth.JWTSecret = testJWTSecret
+	cfg.Server.WriteTimeout = 60
+	cfg.Server.BodyLimit = "lots"
+	cfg.Server.TLSCertFile = "cert.pem"
+	cfg.Database.SSLMode = "on"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"server.write_timeout", "server.body_limit", "server.tls_key_file", "database.sslmode"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
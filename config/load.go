@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables that override config fields.
+// The variable of a field joins the JSON names of its path with underscores,
+// e.g. APP_DATABASE_HOST for database.host; APP_DATABASE_PASSWORD_FILE reads
+// database.password from a file instead.
+const EnvPrefix = "APP_"
+
+// Defaults returns the configuration used for the fields that neither the
+// config file nor the environment sets.
+func Defaults() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     30,
+			IdleTimeout:     120,
+			HandlerTimeout:  120,
+			ShutdownTimeout: 5,
+			BodyLimit:       "10M",
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 1800,
+			ApplicationName: "dot-backend-freelance",
+		},
+		Redis: RedisConfig{
+			Host: "localhost",
+			Port: 6379,
+			TTL:  60,
+		},
+		SoftDelete: SoftDeleteConfig{
+			PurgeIntervalMinutes: 60,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTLMinutes: 15,
+			RefreshTokenTTLHours:  7 * 24,
+		},
+		Cache: CacheConfig{
+			Mode:                CacheModeRedis,
+			Codec:               CacheCodecJSON,
+			KeyPrefix:           "cache:",
+			InvalidationChannel: "cache:invalidations",
+		},
+		Health: HealthConfig{
+			MigrationDir: "migration",
+			CheckTimeout: 2,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "dot-backend-freelance",
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:       "info",
+			Format:      "json",
+			SlowQueryMS: 200,
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours:    24,
+			LockTimeout: 180,
+		},
+		Import: ImportConfig{
+			BatchSize: 500,
+			BodyLimit: "100M",
+			Timeout:   3600,
+		},
+		Export: ExportConfig{
+			Dir:          "exports",
+			MaxSyncRows:  100000,
+			BatchSize:    1000,
+			TTLHours:     24,
+			PollInterval: 5,
+			JobTimeout:   3600,
+		},
+	}
+}
+
+// LoadConfig layers the defaults, the config file at configPath and the
+// APP_ environment variables, each overriding the previous one. The file is
+// YAML when its extension is .yaml or .yml and JSON otherwise; unknown fields
+// are rejected. An empty configPath skips the file. The result is not
+// validated; see Validate.
+func LoadConfig(configPath string) (*Config, error) {
+	cfg := Defaults()
+
+	if configPath != "" {
+		if err := decodeFile(configPath, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func decodeFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// YAML is converted to JSON so that both formats use the json
+		// field names and the same strict decoding.
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to decode config file: %w", err)
+		}
+		if doc == nil {
+			return nil
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("failed to decode config file: %w", err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to decode config file: %w", err)
+	}
+	return nil
+}
+
+// applyEnv sets the fields of v from the environment variables named after
+// them, looked up with lookup.
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + strings.ToUpper(jsonName(field))
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(v.Field(i), name+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := lookup(name)
+		if path, fromFile := lookup(name + "_FILE"); fromFile {
+			if ok {
+				return fmt.Errorf("both %s and %s_FILE are set", name, name)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s_FILE: %w", name, err)
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Redacted returns a copy of c with every secret field that is set replaced
+// by a placeholder, for printing.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Struct:
+			redact(f)
+		case t.Field(i).Tag.Get("secret") == "true" && f.Kind() == reflect.String && f.String() != "":
+			f.SetString("[REDACTED]")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/gommon/bytes"
+)
+
+// Validate checks the fields the server needs and reports every problem at
+// once, each prefixed with the field's path.
+func (c *Config) Validate() error {
+	var errs errList
+
+	requireString(&errs, "server.address", c.Server.Address)
+	nonNegative(&errs, "server.read_timeout", c.Server.ReadTimeout)
+	nonNegative(&errs, "server.write_timeout", c.Server.WriteTimeout)
+	nonNegative(&errs, "server.idle_timeout", c.Server.IdleTimeout)
+	nonNegative(&errs, "server.handler_timeout", c.Server.HandlerTimeout)
+	positive(&errs, "server.shutdown_timeout", c.Server.ShutdownTimeout)
+	if c.Server.WriteTimeout > 0 && c.Server.HandlerTimeout > 0 && c.Server.WriteTimeout <= c.Server.HandlerTimeout {
+		errs.addf("server.write_timeout: must be greater than server.handler_timeout (%d) so handlers can still respond", c.Server.HandlerTimeout)
+	}
+	if c.Server.BodyLimit != "" {
+		if _, err := bytes.Parse(c.Server.BodyLimit); err != nil {
+			errs.addf("server.body_limit: must be a size such as 10M, got %q", c.Server.BodyLimit)
+		}
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs.addf("server.tls_cert_file, server.tls_key_file: must be set together")
+	}
+
+	requireString(&errs, "database.host", c.Database.Host)
+	requirePort(&errs, "database.port", c.Database.Port)
+	requireString(&errs, "database.user", c.Database.User)
+	requireString(&errs, "database.dbname", c.Database.DBName)
+	oneOf(&errs, "database.sslmode", c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	nonNegative(&errs, "database.max_open_conns", c.Database.MaxOpenConns)
+	nonNegative(&errs, "database.max_idle_conns", c.Database.MaxIdleConns)
+	nonNegative(&errs, "database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	nonNegative(&errs, "database.statement_timeout_ms", c.Database.StatementTimeoutMS)
+
+	if c.Cache.Mode != CacheModeMemory {
+		requireString(&errs, "redis.host", c.Redis.Host)
+		requirePort(&errs, "redis.port", c.Redis.Port)
+	}
+	nonNegative(&errs, "redis.ttl", c.Redis.TTL)
+	nonNegative(&errs, "redis.db", c.Redis.DB)
+	nonNegative(&errs, "redis.pool_size", c.Redis.PoolSize)
+	nonNegative(&errs, "redis.dial_timeout", c.Redis.DialTimeout)
+	nonNegative(&errs, "redis.read_timeout", c.Redis.ReadTimeout)
+	nonNegative(&errs, "redis.write_timeout", c.Redis.WriteTimeout)
+
+	nonNegative(&errs, "soft_delete.retention_days", c.SoftDelete.RetentionDays)
+	nonNegative(&errs, "soft_delete.purge_interval_minutes", c.SoftDelete.PurgeIntervalMinutes)
+
+	requireString(&errs, "auth.jwt_secret", c.Auth.JWTSecret)
+	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < minJWTSecretLen {
+		errs.addf("auth.jwt_secret: must be at least %d characters", minJWTSecretLen)
+	}
+	notPlaceholder(&errs, "auth.jwt_secret", c.Auth.JWTSecret)
+	positive(&errs, "auth.access_token_ttl_minutes", c.Auth.AccessTokenTTLMinutes)
+	positive(&errs, "auth.refresh_token_ttl_hours", c.Auth.RefreshTokenTTLHours)
+	if c.Auth.AdminEmail != "" && len(c.Auth.AdminPassword) < 8 {
+		errs.addf("auth.admin_password: must be at least 8 characters when auth.admin_email is set")
+	}
+	notPlaceholder(&errs, "auth.admin_password", c.Auth.AdminPassword)
+
+	oneOf(&errs, "cache.mode", c.Cache.Mode, CacheModeRedis, CacheModeMemory, CacheModeLayered)
+	oneOf(&errs, "cache.codec", c.Cache.Codec, CacheCodecJSON, CacheCodecMsgpack)
+	nonNegative(&errs, "cache.max_entries", c.Cache.MaxEntries)
+	nonNegative(&errs, "cache.max_bytes", int(c.Cache.MaxBytes))
+	nonNegative(&errs, "cache.local_ttl", c.Cache.LocalTTL)
+	nonNegative(&errs, "cache.stale_ttl", c.Cache.StaleTTL)
+	if c.Cache.EarlyRefreshBeta < 0 {
+		errs.addf("cache.early_refresh_beta: must not be negative")
+	}
+
+	nonNegative(&errs, "health.check_timeout", c.Health.CheckTimeout)
+	nonNegative(&errs, "health.drain_seconds", c.Health.DrainSeconds)
+
+	oneOf(&errs, "tracing.exporter", c.Tracing.Exporter, "none", "stdout", "file", "otlp")
+	if c.Tracing.Exporter == "file" {
+		requireString(&errs, "tracing.file_path", c.Tracing.FilePath)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs.addf("tracing.sample_ratio: must be between 0 and 1")
+	}
+
+	oneOf(&errs, "log.level", strings.ToLower(c.Log.Level), "debug", "info", "warn", "error")
+	oneOf(&errs, "log.format", strings.ToLower(c.Log.Format), "json", "text")
+
+	positive(&errs, "idempotency.ttl_hours", c.Idempotency.TTLHours)
+	positive(&errs, "idempotency.lock_timeout", c.Idempotency.LockTimeout)
+	if c.Server.HandlerTimeout > 0 && c.Idempotency.LockTimeout <= c.Server.HandlerTimeout {
+		errs.addf("idempotency.lock_timeout: must be greater than server.handler_timeout (%d) so running requests keep their key", c.Server.HandlerTimeout)
+	}
+
+	positive(&errs, "import.batch_size", c.Import.BatchSize)
+	nonNegative(&errs, "import.timeout", c.Import.Timeout)
+	if c.Import.BodyLimit != "" {
+		if _, err := bytes.Parse(c.Import.BodyLimit); err != nil {
+			errs.addf("import.body_limit: must be a size such as 100M, got %q", c.Import.BodyLimit)
+		}
+	}
+
+	requireString(&errs, "export.dir", c.Export.Dir)
+	positive(&errs, "export.max_sync_rows", c.Export.MaxSyncRows)
+	positive(&errs, "export.batch_size", c.Export.BatchSize)
+	positive(&errs, "export.ttl_hours", c.Export.TTLHours)
+	positive(&errs, "export.poll_interval", c.Export.PollInterval)
+	positive(&errs, "export.job_timeout", c.Export.JobTimeout)
+
+	return errs.err()
+}
+
+// minJWTSecretLen is the shortest accepted JWT secret: 32 bytes, the size of
+// an HS256 key.
+const minJWTSecretLen = 32
+
+// placeholders are values shipped in examples and docs, which must never
+// protect a deployment.
+var placeholders = []string{"change-me", "change-me-too", "changeme", "secret", "password"}
+
+// errList collects validation errors.
+type errList []error
+
+func (l *errList) addf(format string, args ...interface{}) {
+	*l = append(*l, fmt.Errorf(format, args...))
+}
+
+func (l errList) err() error {
+	return errors.Join(l...)
+}
+
+func requireString(errs *errList, path, value string) {
+	if strings.TrimSpace(value) == "" {
+		errs.addf("%s: is required", path)
+	}
+}
+
+func requirePort(errs *errList, path string, port int) {
+	if port < 1 || port > 65535 {
+		errs.addf("%s: must be between 1 and 65535, got %d", path, port)
+	}
+}
+
+func positive(errs *errList, path string, n int) {
+	if n <= 0 {
+		errs.addf("%s: must be positive, got %d", path, n)
+	}
+}
+
+func nonNegative(errs *errList, path string, n int) {
+	if n < 0 {
+		errs.addf("%s: must not be negative, got %d", path, n)
+	}
+}
+
+func notPlaceholder(errs *errList, path, value string) {
+	for _, p := range placeholders {
+		if strings.EqualFold(strings.TrimSpace(value), p) {
+			errs.addf("%s: must not be the placeholder %q", path, p)
+			return
+		}
+	}
+}
+
+func oneOf(errs *errList, path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	errs.addf("%s: must be one of %s, got %q", path, strings.Join(allowed, ", "), value)
+}
@@ -3,7 +3,7 @@
 # Menunggu Postgres siap
 echo "Menunggu Postgres..."
 
-while ! nc -z $DB_HOST $DB_PORT; do
+while ! nc -z $APP_DATABASE_HOST $APP_DATABASE_PORT; do
   sleep 1
 done
 
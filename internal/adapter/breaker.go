@@ -0,0 +1,324 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BreakerOptions configures a CircuitBreaker. Zero values use the defaults.
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive connection errors that
+	// opens the circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a single call is
+	// let through to probe the store. Defaults to 10s.
+	OpenTimeout time.Duration
+	// MaxPending bounds the invalidations kept while the store is
+	// unreachable. Past it, the whole store is cleared on recovery instead.
+	// Defaults to 10000.
+	MaxPending int
+}
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerStatus is a snapshot of a CircuitBreaker.
+type BreakerStatus struct {
+	State                string     `json:"state"`
+	Failures             int        `json:"failures"`
+	OpenedAt             *time.Time `json:"opened_at,omitempty"`
+	PendingInvalidations int        `json:"pending_invalidations"`
+	// DroppedInvalidations counts the invalidations past MaxPending since
+	// the store was last cleared; while it is not zero, FlushPending is set.
+	DroppedInvalidations int  `json:"dropped_invalidations"`
+	FlushPending         bool `json:"flush_pending"`
+}
+
+// CircuitBreaker guards a remote cache. After FailureThreshold consecutive
+// connection errors it opens: reads are treated as misses and writes are
+// skipped, so requests are served from the database without waiting on the
+// cache. Keys written or deleted meanwhile are remembered and deleted once
+// the cache is reachable again, before any other call goes through, so no
+// entry outlives a change made during the outage. When more than MaxPending
+// keys change, the whole cache is cleared instead; until that succeeds the
+// breaker stays open, since the cache may hold stale entries.
+type CircuitBreaker struct {
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	pending  map[string]struct{}
+	dropped  int
+	// flush is set once keys were dropped: the store must be cleared before
+	// it is used again.
+	flush bool
+}
+
+func NewCircuitBreaker(opts BreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 10 * time.Second
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 10000
+	}
+	return &CircuitBreaker{
+		opts:    opts,
+		now:     time.Now,
+		state:   BreakerClosed,
+		pending: make(map[string]struct{}),
+	}
+}
+
+// Status returns the current state of the breaker.
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		State:                b.state,
+		Failures:             b.failures,
+		PendingInvalidations: len(b.pending),
+		DroppedInvalidations: b.dropped,
+		FlushPending:         b.flush,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// allow reports whether a call may reach the store. Once OpenTimeout has
+// passed, an open breaker lets a single call through as a probe. The returned
+// keys are pending invalidations the caller must replay first, or, when
+// flush is set, the caller must clear the store first; either is handed to
+// one caller only.
+func (b *CircuitBreaker) allow() (ok bool, keys []string, flush bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.opts.OpenTimeout {
+			return false, nil, false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, nil, false
+		}
+		b.probing = true
+	}
+
+	if b.flush {
+		b.flush = false
+		b.dropped = 0
+		b.pending = make(map[string]struct{})
+		return true, nil, true
+	}
+	if len(b.pending) == 0 {
+		return true, nil, false
+	}
+	keys = make([]string, 0, len(b.pending))
+	for key := range b.pending {
+		keys = append(keys, key)
+	}
+	b.pending = make(map[string]struct{})
+	return true, keys, false
+}
+
+// done records the outcome of a call let through by allow.
+func (b *CircuitBreaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !isUnavailable(err) {
+		if b.state != BreakerClosed {
+			slog.Info("cache circuit closed")
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.opts.FailureThreshold) {
+		if b.state == BreakerClosed {
+			slog.Warn("cache circuit open", "failures", b.failures, "error", err)
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// invalidate remembers keys to delete once the store is reachable again.
+// Keys past MaxPending are not kept; the store is cleared instead.
+func (b *CircuitBreaker) invalidate(keys ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		if b.flush {
+			b.dropped++
+			continue
+		}
+		if _, ok := b.pending[key]; ok {
+			continue
+		}
+		if len(b.pending) >= b.opts.MaxPending {
+			slog.Warn("too many pending cache invalidations, the cache will be cleared on recovery", "max_pending", b.opts.MaxPending)
+			b.flush = true
+			b.dropped++
+			b.pending = make(map[string]struct{})
+			continue
+		}
+		b.pending[key] = struct{}{}
+	}
+}
+
+// trip opens the breaker after the store could not be cleared, whatever the
+// error, so that it is bypassed rather than cleared on every call until a
+// probe succeeds.
+func (b *CircuitBreaker) trip(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		slog.Warn("cache circuit open, the cache could not be cleared", "error", err)
+	}
+	b.probing = false
+	b.failures++
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+}
+
+// requireFlush makes the next call clear the store, after a clear failed.
+func (b *CircuitBreaker) requireFlush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.flush = true
+	b.pending = make(map[string]struct{})
+}
+
+// isUnavailable reports whether err means the store could not be reached, as
+// opposed to a miss, an error reply from Redis or a canceled request.
+func isUnavailable(err error) bool {
+	if err == nil || errors.Is(err, ErrCacheMiss) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var reply redis.Error
+	return !errors.As(err, &reply)
+}
+
+// breakerStore is a byteStore guarded by a CircuitBreaker.
+type breakerStore struct {
+	store   byteStore
+	breaker *CircuitBreaker
+}
+
+// guard wraps store with breaker, or returns it as is when breaker is nil.
+func guard(store byteStore, breaker *CircuitBreaker) byteStore {
+	if breaker == nil {
+		return store
+	}
+	return &breakerStore{store: store, breaker: breaker}
+}
+
+// call runs op unless the breaker is open, replaying pending invalidations
+// or clearing the store first. It reports whether op ran.
+func (s *breakerStore) call(ctx context.Context, op func() error) (bool, error) {
+	ok, pending, flush := s.breaker.allow()
+	if !ok {
+		return false, nil
+	}
+	if flush {
+		if err := s.store.clear(ctx); err != nil {
+			s.breaker.requireFlush()
+			if errors.Is(err, context.Canceled) {
+				s.breaker.done(err)
+			} else {
+				s.breaker.trip(err)
+			}
+			return false, err
+		}
+	}
+	if len(pending) > 0 {
+		if err := s.store.del(ctx, pending...); err != nil {
+			s.breaker.invalidate(pending...)
+			s.breaker.done(err)
+			return false, err
+		}
+	}
+	err := op()
+	s.breaker.done(err)
+	return true, err
+}
+
+func (s *breakerStore) get(ctx context.Context, key string) ([]byte, error) {
+	var val []byte
+	ran, err := s.call(ctx, func() error {
+		var err error
+		val, err = s.store.get(ctx, key)
+		return err
+	})
+	if !ran && err == nil {
+		return nil, ErrCacheMiss
+	}
+	return val, err
+}
+
+// set skips the write while the breaker is open. The key is still
+// invalidated later, since the skipped write may have been meant to replace
+// a value the store still holds.
+func (s *breakerStore) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ran, err := s.call(ctx, func() error {
+		return s.store.set(ctx, key, value, ttl)
+	})
+	if !ran || isUnavailable(err) {
+		s.breaker.invalidate(key)
+		return nil
+	}
+	return err
+}
+
+func (s *breakerStore) del(ctx context.Context, keys ...string) error {
+	ran, err := s.call(ctx, func() error {
+		return s.store.del(ctx, keys...)
+	})
+	if !ran || isUnavailable(err) {
+		s.breaker.invalidate(keys...)
+		return nil
+	}
+	return err
+}
+
+// clear empties the store. While the breaker is open it is cleared on
+// recovery instead.
+func (s *breakerStore) clear(ctx context.Context) error {
+	ran, err := s.call(ctx, func() error {
+		return s.store.clear(ctx)
+	})
+	if !ran || isUnavailable(err) {
+		s.breaker.requireFlush()
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errConnRefused = errors.New("dial tcp: connection refused")
+
+// replyError is an error reply from Redis.
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+
+func (replyError) RedisError() {}
+
+// flakyStore fails every call with errConnRefused while down is set, and
+// clear with clearErr when it is set.
+type flakyStore struct {
+	*memoryCache
+	down     bool
+	clearErr error
+	calls    int
+}
+
+func (s *flakyStore) get(ctx context.Context, key string) ([]byte, error) {
+	s.calls++
+	if s.down {
+		return nil, errConnRefused
+	}
+	return s.memoryCache.get(ctx, key)
+}
+
+func (s *flakyStore) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.calls++
+	if s.down {
+		return errConnRefused
+	}
+	return s.memoryCache.set(ctx, key, value, ttl)
+}
+
+func (s *flakyStore) del(ctx context.Context, keys ...string) error {
+	s.calls++
+	if s.down {
+		return errConnRefused
+	}
+	return s.memoryCache.del(ctx, keys...)
+}
+
+func (s *flakyStore) clear(ctx context.Context) error {
+	s.calls++
+	if s.down {
+		return errConnRefused
+	}
+	if s.clearErr != nil {
+		return s.clearErr
+	}
+	return s.memoryCache.clear(ctx)
+}
+
+func newBreakerFixture() (*flakyStore, *CircuitBreaker, *breakerStore, *time.Time) {
+	store := &flakyStore{memoryCache: newMemoryCache(MemoryCacheOptions{})}
+	breaker := NewCircuitBreaker(BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+	return store, breaker, &breakerStore{store: store, breaker: breaker}, &now
+}
+
+func TestBreakerOpensAndBypassesStore(t *testing.T) {
+	ctx := context.Background()
+	store, breaker, s, _ := newBreakerFixture()
+	store.down = true
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.get(ctx, "k"); !errors.Is(err, errConnRefused) {
+			t.Fatalf("expected the connection error, got %v", err)
+		}
+	}
+	if state := breaker.Status().State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", state)
+	}
+
+	calls := store.calls
+	if _, err := s.get(ctx, "k"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected a miss while open, got %v", err)
+	}
+	if err := s.set(ctx, "k", []byte("v"), 0); err != nil {
+		t.Fatalf("expected set to be skipped, got %v", err)
+	}
+	if err := s.del(ctx, "other"); err != nil {
+		t.Fatalf("expected delete to be deferred, got %v", err)
+	}
+	if store.calls != calls {
+		t.Fatalf("expected no calls to reach the store while open")
+	}
+	if pending := breaker.Status().PendingInvalidations; pending != 2 {
+		t.Fatalf("expected 2 pending invalidations, got %d", pending)
+	}
+}
+
+func TestBreakerReplaysInvalidationsOnRecovery(t *testing.T) {
+	ctx := context.Background()
+	store, breaker, s, now := newBreakerFixture()
+	store.memoryCache.set(ctx, "user:1", []byte("old"), 0)
+
+	store.down = true
+	for i := 0; i < 2; i++ {
+		if err := s.del(ctx, "user:1"); err != nil {
+			t.Fatalf("expected delete not to fail, got %v", err)
+		}
+	}
+	if state := breaker.Status().State; state != BreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", state)
+	}
+
+	// A failed probe keeps the breaker open and the invalidation pending.
+	*now = now.Add(time.Minute)
+	if _, err := s.get(ctx, "user:1"); !errors.Is(err, errConnRefused) {
+		t.Fatalf("expected the probe to fail, got %v", err)
+	}
+	status := breaker.Status()
+	if status.State != BreakerOpen || status.PendingInvalidations != 1 {
+		t.Fatalf("unexpected status after a failed probe %+v", status)
+	}
+
+	store.down = false
+	*now = now.Add(time.Minute)
+	if _, err := s.get(ctx, "user:1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected the stale entry to be invalidated, got %v", err)
+	}
+	status = breaker.Status()
+	if status.State != BreakerClosed || status.PendingInvalidations != 0 {
+		t.Fatalf("unexpected status after recovery %+v", status)
+	}
+}
+
+func TestBreakerBoundsPendingInvalidations(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerOptions{MaxPending: 2})
+	breaker.invalidate("a", "b", "a")
+
+	status := breaker.Status()
+	if status.PendingInvalidations != 2 || status.FlushPending {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	breaker.invalidate("c", "d")
+	status = breaker.Status()
+	if status.PendingInvalidations != 0 || status.DroppedInvalidations != 2 || !status.FlushPending {
+		t.Fatalf("expected the overflow to require a flush, got %+v", status)
+	}
+}
+
+func TestBreakerClearsStoreAfterOverflow(t *testing.T) {
+	ctx := context.Background()
+	store, breaker, s, now := newBreakerFixture()
+	breaker.opts.MaxPending = 1
+	store.memoryCache.set(ctx, "user:1", []byte("old"), 0)
+	store.memoryCache.set(ctx, "user:2", []byte("old"), 0)
+
+	store.down = true
+	for i := 0; i < 2; i++ {
+		if err := s.del(ctx, "user:1", "user:2"); err != nil {
+			t.Fatalf("expected delete not to fail, got %v", err)
+		}
+	}
+	if status := breaker.Status(); status.State != BreakerOpen || !status.FlushPending {
+		t.Fatalf("unexpected status during the outage %+v", status)
+	}
+
+	// A failed probe keeps the flush pending.
+	*now = now.Add(time.Minute)
+	if _, err := s.get(ctx, "user:3"); !errors.Is(err, errConnRefused) {
+		t.Fatalf("expected the probe to fail, got %v", err)
+	}
+	if status := breaker.Status(); !status.FlushPending {
+		t.Fatalf("expected the flush to stay pending, got %+v", status)
+	}
+
+	store.down = false
+	*now = now.Add(time.Minute)
+	for _, key := range []string{"user:1", "user:2"} {
+		if _, err := s.get(ctx, key); !errors.Is(err, ErrCacheMiss) {
+			t.Fatalf("expected %s to be cleared, got %v", key, err)
+		}
+	}
+	status := breaker.Status()
+	if status.State != BreakerClosed || status.FlushPending || status.DroppedInvalidations != 0 {
+		t.Fatalf("unexpected status after recovery %+v", status)
+	}
+}
+
+func TestBreakerStaysOpenWhileTheStoreCannotBeCleared(t *testing.T) {
+	ctx := context.Background()
+	store, breaker, s, now := newBreakerFixture()
+	breaker.flush = true
+	store.clearErr = replyError("ERR unknown command")
+
+	if _, err := s.get(ctx, "user:1"); !errors.Is(err, store.clearErr) {
+		t.Fatalf("expected the clear error, got %v", err)
+	}
+	status := breaker.Status()
+	if status.State != BreakerOpen || !status.FlushPending {
+		t.Fatalf("expected the breaker to open with the flush pending, got %+v", status)
+	}
+
+	calls := store.calls
+	for i := 0; i < 3; i++ {
+		if _, err := s.get(ctx, "user:1"); !errors.Is(err, ErrCacheMiss) {
+			t.Fatalf("expected a miss while open, got %v", err)
+		}
+	}
+	if store.calls != calls {
+		t.Fatalf("expected the clear not to be retried before the open timeout")
+	}
+
+	store.clearErr = nil
+	*now = now.Add(time.Minute)
+	if _, err := s.get(ctx, "user:1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected the probe to clear the store, got %v", err)
+	}
+	if status := breaker.Status(); status.State != BreakerClosed || status.FlushPending {
+		t.Fatalf("unexpected status after recovery %+v", status)
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	if isUnavailable(ErrCacheMiss) || isUnavailable(context.Canceled) || isUnavailable(nil) {
+		t.Fatal("expected misses and canceled requests not to count as failures")
+	}
+	if !isUnavailable(errConnRefused) || !isUnavailable(context.DeadlineExceeded) {
+		t.Fatal("expected connection errors and timeouts to count as failures")
+	}
+}
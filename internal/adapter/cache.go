@@ -0,0 +1,140 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrCacheMiss is returned by Get when the key is not cached.
+var ErrCacheMiss = errors.New("cache miss")
+
+type CacheManager interface {
+	// Get decodes the value cached under key into dest. It returns
+	// ErrCacheMiss when the key is not cached.
+	Get(ctx context.Context, key string, dest interface{}) error
+	// Set caches value under key for ttl, or for the cache's default TTL
+	// when ttl is zero.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// GetOrLoad returns the value cached under key, or calls load and caches its
+// result on a miss. The cache is best effort: when it fails, the loaded value
+// is still returned.
+func GetOrLoad[T any](ctx context.Context, cacheManager CacheManager, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	err := cacheManager.Get(ctx, key, &value)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		logging.FromContext(ctx).WarnContext(ctx, "cache get failed", "key", key, "error", err)
+	}
+
+	value, err = load(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	if err := cacheManager.Set(ctx, key, value, ttl); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "cache set failed", "key", key, "error", err)
+	}
+	return value, nil
+}
+
+// Codec encodes the values stored in a cache.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec uses the json struct tags, so fields are named and omitted the
+// same way as with JSONCodec.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// byteStore is a cache of encoded values. Implementations return ErrCacheMiss
+// for keys they do not hold.
+type byteStore interface {
+	get(ctx context.Context, key string) ([]byte, error)
+	set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	del(ctx context.Context, keys ...string) error
+	// clear removes every entry.
+	clear(ctx context.Context) error
+}
+
+// codecCache implements CacheManager on top of a byteStore.
+type codecCache struct {
+	store byteStore
+	codec Codec
+	ttl   time.Duration
+}
+
+func newCodecCache(store byteStore, codec Codec, ttl time.Duration) *codecCache {
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return &codecCache{store: store, codec: codec, ttl: ttl}
+}
+
+func (c *codecCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.store.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return c.codec.Unmarshal(data, dest)
+}
+
+func (c *codecCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	return c.store.set(ctx, key, data, ttl)
+}
+
+func (c *codecCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.store.del(ctx, keys...)
+}
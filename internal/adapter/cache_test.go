@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := entity.Order{
+		ID:        7,
+		OrderName: "Logo",
+		UserID:    3,
+		Status:    entity.OrderStatusSubmitted,
+		Currency:  "USD",
+		CreatedAt: created,
+		User:      entity.User{ID: 3, Name: "Alice", PasswordHash: "secret"},
+		Items:     []entity.OrderItem{{ID: 1, Description: "Design", Quantity: 2, UnitPrice: 1500, Currency: "USD"}},
+	}
+
+	for name, codec := range map[string]Codec{"json": JSONCodec, "msgpack": MsgpackCodec} {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.Marshal(order)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var got entity.Order
+			if err := codec.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			if got.ID != order.ID || got.Status != order.Status || !got.CreatedAt.Equal(created) {
+				t.Fatalf("unexpected order %+v", got)
+			}
+			if len(got.Items) != 1 || got.Items[0].UnitPrice != 1500 {
+				t.Fatalf("unexpected items %+v", got.Items)
+			}
+			if got.User.PasswordHash != "" {
+				t.Fatalf("expected the password hash not to be cached")
+			}
+		})
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(MemoryCacheOptions{}, JSONCodec)
+
+	loads := 0
+	load := func(context.Context) ([]string, error) {
+		loads++
+		return []string{"a", "b"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := GetOrLoad(ctx, cache, "letters", 0, load)
+		if err != nil {
+			t.Fatalf("GetOrLoad: %v", err)
+		}
+		if len(got) != 2 || got[1] != "b" {
+			t.Fatalf("unexpected value %v", got)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("expected one load, got %d", loads)
+	}
+
+	wantErr := errors.New("boom")
+	if _, err := GetOrLoad(ctx, cache, "failing", 0, func(context.Context) (int, error) {
+		return 0, wantErr
+	}); !errors.Is(err, wantErr) {
+		t.Fatalf("expected the load error, got %v", err)
+	}
+	var v int
+	if err := cache.Get(ctx, "failing", &v); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected a failed load not to be cached, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package adapter
+
+import (
+	"errors"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// constraintErrors maps database constraints to the domain error reported
+// when they are violated.
+var constraintErrors = map[string]*apperror.Error{
+	"idx_users_email_active": entity.ErrEmailTaken,
+	"orders_user_id_fkey":    entity.ErrOrderUserNotFound,
+}
+
+// translateError turns constraint violations reported by Postgres into domain
+// errors. Other errors are returned unchanged.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	if domainErr, ok := constraintErrors[pgErr.ConstraintName]; ok {
+		return apperror.Wrap(domainErr, err)
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return apperror.Wrap(apperror.Conflict("already_exists", "resource already exists"), err)
+	case pgForeignKeyViolation:
+		return apperror.Wrap(apperror.Validation("invalid_reference", "referenced resource does not exist"), err)
+	}
+	return err
+}
+
+// NotFound replaces gorm.ErrRecordNotFound with domainErr.
+func NotFound(err error, domainErr *apperror.Error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Wrap(domainErr, err)
+	}
+	return err
+}
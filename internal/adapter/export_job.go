@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *entity.ExportJob) error
+	GetByID(ctx context.Context, id uint) (entity.ExportJob, error)
+	// Claim marks the oldest pending job as running and returns it. Jobs
+	// left running since before staleBefore, by a worker that stopped, are
+	// claimed again. It reports false when there is no job to run.
+	Claim(ctx context.Context, now, staleBefore time.Time) (entity.ExportJob, bool, error)
+	// Finish stores the outcome of a claimed job. It fails with
+	// entity.ErrExportJobNotFound when another worker has claimed the job
+	// since.
+	Finish(ctx context.Context, job *entity.ExportJob) error
+	// PurgeExpired deletes the finished jobs that expired before now and
+	// returns them, so that their files can be removed.
+	PurgeExpired(ctx context.Context, now time.Time) ([]entity.ExportJob, error)
+}
+
+type exportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) ExportJobRepository {
+	return &exportJobRepository{db}
+}
+
+func (r *exportJobRepository) Create(ctx context.Context, job *entity.ExportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *exportJobRepository) GetByID(ctx context.Context, id uint) (entity.ExportJob, error) {
+	var job entity.ExportJob
+	err := r.db.WithContext(ctx).Take(&job, id).Error
+	return job, NotFound(err, entity.ErrExportJobNotFound)
+}
+
+func (r *exportJobRepository) Claim(ctx context.Context, now, staleBefore time.Time) (entity.ExportJob, bool, error) {
+	var job entity.ExportJob
+	result := r.db.WithContext(ctx).Raw(`
+		UPDATE export_jobs SET status = ?, started_at = ?
+		WHERE id = (
+			SELECT id FROM export_jobs
+			WHERE status = ? OR (status = ? AND started_at < ?)
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		entity.ExportJobRunning, now, entity.ExportJobPending, entity.ExportJobRunning, staleBefore,
+	).Scan(&job)
+	if result.Error != nil {
+		return entity.ExportJob{}, false, result.Error
+	}
+	return job, result.RowsAffected == 1, nil
+}
+
+func (r *exportJobRepository) Finish(ctx context.Context, job *entity.ExportJob) error {
+	result := r.db.WithContext(ctx).Model(&entity.ExportJob{}).
+		Where("id = ? AND status = ? AND started_at = ?", job.ID, entity.ExportJobRunning, job.StartedAt).
+		Updates(map[string]interface{}{
+			"status":       job.Status,
+			"rows":         job.Rows,
+			"file_name":    job.FileName,
+			"error":        job.Error,
+			"completed_at": job.CompletedAt,
+			"expires_at":   job.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entity.ErrExportJobNotFound
+	}
+	return nil
+}
+
+func (r *exportJobRepository) PurgeExpired(ctx context.Context, now time.Time) ([]entity.ExportJob, error) {
+	var jobs []entity.ExportJob
+	err := r.db.WithContext(ctx).Raw(`DELETE FROM export_jobs WHERE expires_at < ? RETURNING *`, now).Scan(&jobs).Error
+	return jobs, err
+}
@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository interface {
+	// Acquire inserts record unless the key is already taken. A key is free
+	// again once it has expired or when the request holding it started
+	// before staleBefore without completing. It reports whether record was
+	// stored.
+	Acquire(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (bool, error)
+	Get(ctx context.Context, userID uint, key string) (entity.IdempotencyKey, error)
+	// Complete stores the response of the request holding the key.
+	Complete(ctx context.Context, record *entity.IdempotencyKey) error
+	// Release frees a key whose request did not complete, so that it can be
+	// retried.
+	Release(ctx context.Context, record *entity.IdempotencyKey) error
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db}
+}
+
+func (r *idempotencyRepository) Acquire(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = '',
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < EXCLUDED.created_at
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < ?)`,
+		record.UserID, record.IdempotencyKey, record.Fingerprint, record.CreatedAt, record.ExpiresAt, staleBefore,
+	)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, userID uint, key string) (entity.IdempotencyKey, error) {
+	var record entity.IdempotencyKey
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		Take(&record).Error
+	return record, NotFound(err, entity.ErrIdempotencyKeyNotFound)
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, record *entity.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Model(&entity.IdempotencyKey{}).
+		Where("user_id = ? AND idempotency_key = ? AND fingerprint = ? AND status_code IS NULL",
+			record.UserID, record.IdempotencyKey, record.Fingerprint).
+		Updates(map[string]interface{}{
+			"status_code":   record.StatusCode,
+			"content_type":  record.ContentType,
+			"location":      record.Location,
+			"etag":          record.ETag,
+			"response_body": record.ResponseBody,
+		}).Error
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, record *entity.IdempotencyKey) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND idempotency_key = ? AND fingerprint = ? AND status_code IS NULL",
+			record.UserID, record.IdempotencyKey, record.Fingerprint).
+		Delete(&entity.IdempotencyKey{}).Error
+}
+
+func (r *idempotencyRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&entity.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,142 @@
+package adapter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+// layeredCache checks an in-process cache before Redis. Every write is
+// published on a Redis channel so that the other app instances drop their
+// local copy of the key. A missed message (e.g. during a reconnect) leaves a
+// stale local entry for at most the local TTL, so keep it short.
+type layeredCache struct {
+	local    *memoryCache
+	remote   *redisCache
+	channel  string
+	origin   string
+	localTTL time.Duration
+}
+
+// invalidationMessage names the keys to drop, or every key when All is set.
+type invalidationMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+	All    bool     `json:"all,omitempty"`
+}
+
+// NewLayeredCache returns a two-tier cache, storing its Redis keys under
+// prefix, and starts listening for invalidations on channel until ctx is
+// done. When breaker is not nil, both
+// tiers are bypassed while Redis is unreachable, since the local tier misses
+// the other instances' invalidations meanwhile.
+func NewLayeredCache(ctx context.Context, client *redis.Client, prefix string, ttl time.Duration, local MemoryCacheOptions, channel string, codec Codec, breaker *CircuitBreaker) CacheManager {
+	c := &layeredCache{
+		local:    newMemoryCache(local),
+		remote:   &redisCache{client: client, prefix: prefix},
+		channel:  channel,
+		origin:   newInstanceID(),
+		localTTL: local.TTL,
+	}
+	go c.listen(ctx)
+	return newCodecCache(guard(c, breaker), codec, ttl)
+}
+
+func (c *layeredCache) get(ctx context.Context, key string) ([]byte, error) {
+	if val, err := c.local.get(ctx, key); err == nil {
+		return val, nil
+	}
+
+	val, err := c.remote.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.local.set(ctx, key, val, c.localTTL)
+	return val, nil
+}
+
+func (c *layeredCache) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	localTTL := c.localTTL
+	if ttl > 0 && (localTTL <= 0 || ttl < localTTL) {
+		localTTL = ttl
+	}
+	c.local.set(ctx, key, value, localTTL)
+
+	return c.publish(ctx, key)
+}
+
+func (c *layeredCache) del(ctx context.Context, keys ...string) error {
+	c.local.del(ctx, keys...)
+	if err := c.remote.del(ctx, keys...); err != nil {
+		return err
+	}
+	return c.publish(ctx, keys...)
+}
+
+func (c *layeredCache) clear(ctx context.Context) error {
+	c.local.clear(ctx)
+	if err := c.remote.clear(ctx); err != nil {
+		return err
+	}
+	return c.send(ctx, invalidationMessage{Origin: c.origin, All: true})
+}
+
+func (c *layeredCache) publish(ctx context.Context, keys ...string) error {
+	return c.send(ctx, invalidationMessage{Origin: c.origin, Keys: keys})
+}
+
+func (c *layeredCache) send(ctx context.Context, m invalidationMessage) error {
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.remote.client.Publish(ctx, c.channel, msg).Err()
+}
+
+func (c *layeredCache) listen(ctx context.Context) {
+	sub := c.remote.client.Subscribe(ctx, c.channel)
+	defer sub.Close()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+			var msg invalidationMessage
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				logging.FromContext(ctx).WarnContext(ctx, "invalid cache invalidation message", "error", err)
+				continue
+			}
+			if msg.Origin == c.origin {
+				continue
+			}
+			if msg.All {
+				c.local.clear(ctx)
+			} else {
+				c.local.del(ctx, msg.Keys...)
+			}
+		}
+	}
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
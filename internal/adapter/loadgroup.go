@@ -0,0 +1,142 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"golang.org/x/sync/singleflight"
+)
+
+// RefreshPolicy controls how a LoadGroup keeps entries fresh.
+//
+// An entry is fresh for TTL. With Beta > 0 it is refreshed early, before TTL
+// runs out, with a probability that grows as expiry nears and with the time
+// the last load took (the XFetch algorithm); 1 is a good default. With
+// StaleTTL > 0 an expired entry is still served for StaleTTL while a single
+// goroutine refreshes it in the background. A zero TTL leaves expiry to the
+// cache's default TTL. LoadTimeout bounds a single load and defaults to 30s.
+type RefreshPolicy struct {
+	TTL         time.Duration
+	StaleTTL    time.Duration
+	Beta        float64
+	LoadTimeout time.Duration
+}
+
+const defaultLoadTimeout = 30 * time.Second
+
+// LoadGroup is a cache-aside loader that coalesces concurrent loads of the
+// same key, so an expired key costs one database query instead of one per
+// request.
+type LoadGroup struct {
+	cache  CacheManager
+	policy RefreshPolicy
+	flight singleflight.Group
+	now    func() time.Time
+	rand   func() float64
+}
+
+func NewLoadGroup(cache CacheManager, policy RefreshPolicy) *LoadGroup {
+	if policy.LoadTimeout <= 0 {
+		policy.LoadTimeout = defaultLoadTimeout
+	}
+	return &LoadGroup{
+		cache:  cache,
+		policy: policy,
+		now:    time.Now,
+		rand:   rand.Float64,
+	}
+}
+
+type loadEnvelope[T any] struct {
+	Value     T             `json:"value"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Delta     time.Duration `json:"delta"`
+}
+
+// Load returns the value cached under key, calling load on a miss. Callers
+// waiting on the same load share its result, so they must not modify it.
+func Load[T any](ctx context.Context, g *LoadGroup, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	var env loadEnvelope[T]
+	err := g.cache.Get(ctx, key, &env)
+	if err == nil {
+		now := g.now()
+		switch {
+		case env.ExpiresAt.IsZero():
+			return env.Value, nil
+		case now.Before(env.ExpiresAt):
+			if g.refreshEarly(now, env.ExpiresAt, env.Delta) {
+				refreshInBackground(ctx, g, key, load)
+			}
+			return env.Value, nil
+		case g.policy.StaleTTL > 0 && now.Before(env.ExpiresAt.Add(g.policy.StaleTTL)):
+			refreshInBackground(ctx, g, key, load)
+			return env.Value, nil
+		}
+	} else if !errors.Is(err, ErrCacheMiss) {
+		logging.FromContext(ctx).WarnContext(ctx, "cache get failed", "key", key, "error", err)
+	}
+
+	ch := g.flight.DoChan(key, func() (interface{}, error) {
+		return refresh(ctx, g, key, load)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			var zero T
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// refreshEarly reports whether an entry expiring at expiresAt should be
+// refreshed now: now - delta*beta*ln(rand) >= expiresAt.
+func (g *LoadGroup) refreshEarly(now, expiresAt time.Time, delta time.Duration) bool {
+	if g.policy.Beta <= 0 || delta <= 0 {
+		return false
+	}
+	gap := -float64(delta) * g.policy.Beta * math.Log(1-g.rand())
+	return !now.Add(time.Duration(gap)).Before(expiresAt)
+}
+
+func refreshInBackground[T any](ctx context.Context, g *LoadGroup, key string, load func(ctx context.Context) (T, error)) {
+	go g.flight.Do(key, func() (interface{}, error) {
+		val, err := refresh(ctx, g, key, load)
+		if err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "cache refresh failed", "key", key, "error", err)
+		}
+		return val, err
+	})
+}
+
+// refresh loads and caches the value of key. The load keeps the values of ctx
+// but not its cancellation, because other requests may be waiting on it.
+func refresh[T any](ctx context.Context, g *LoadGroup, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), g.policy.LoadTimeout)
+	defer cancel()
+
+	start := g.now()
+	val, err := load(ctx)
+	if err != nil {
+		return val, err
+	}
+	now := g.now()
+
+	env := loadEnvelope[T]{Value: val, Delta: now.Sub(start)}
+	ttl := g.policy.TTL
+	if ttl > 0 {
+		env.ExpiresAt = now.Add(ttl)
+		ttl += g.policy.StaleTTL
+	}
+	if err := g.cache.Set(ctx, key, env, ttl); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "cache set failed", "key", key, "error", err)
+	}
+	return val, nil
+}
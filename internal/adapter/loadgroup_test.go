@@ -0,0 +1,116 @@
+package adapter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadCoalescesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	g := NewLoadGroup(NewMemoryCache(MemoryCacheOptions{}, JSONCodec), RefreshPolicy{TTL: time.Minute})
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(context.Context) (int, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := Load(ctx, g, "answer", load)
+			if err != nil {
+				t.Errorf("Load: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("expected one load, got %d", n)
+	}
+	for _, v := range results {
+		if v != 42 {
+			t.Fatalf("unexpected results %v", results)
+		}
+	}
+}
+
+func TestLoadServesStaleWhileRefreshing(t *testing.T) {
+	ctx := context.Background()
+	g := NewLoadGroup(NewMemoryCache(MemoryCacheOptions{}, JSONCodec), RefreshPolicy{TTL: time.Minute, StaleTTL: time.Minute})
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return start }
+
+	version := 0
+	refreshed := make(chan struct{}, 1)
+	load := func(context.Context) (int, error) {
+		version++
+		if version > 1 {
+			refreshed <- struct{}{}
+		}
+		return version, nil
+	}
+
+	if v, err := Load(ctx, g, "version", load); err != nil || v != 1 {
+		t.Fatalf("expected the first load, got %d, %v", v, err)
+	}
+
+	expired := start.Add(90 * time.Second)
+	g.now = func() time.Time { return expired }
+	if v, err := Load(ctx, g, "version", load); err != nil || v != 1 {
+		t.Fatalf("expected the stale value, got %d, %v", v, err)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a background refresh")
+	}
+	// The refresh caches its value after load returns; wait for it.
+	deadline := time.Now().Add(time.Second)
+	for {
+		v, err := Load(ctx, g, "version", load)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the refreshed value, got %d", v)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefreshEarly(t *testing.T) {
+	g := NewLoadGroup(nil, RefreshPolicy{TTL: time.Minute, Beta: 1})
+	// -ln(1-r) == 1, so the entry is refreshed once it is within one load
+	// duration of expiring.
+	g.rand = func() float64 { return 1 - math.Exp(-1) }
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if !g.refreshEarly(now, now.Add(500*time.Millisecond), time.Second) {
+		t.Fatal("expected an entry about to expire to be refreshed early")
+	}
+	if g.refreshEarly(now, now.Add(2*time.Second), time.Second) {
+		t.Fatal("expected an entry far from expiry not to be refreshed")
+	}
+
+	g.policy.Beta = 0
+	if g.refreshEarly(now, now.Add(500*time.Millisecond), time.Second) {
+		t.Fatal("expected a zero beta to disable early refresh")
+	}
+}
@@ -0,0 +1,126 @@
+package adapter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCacheOptions limits an in-process cache. Zero MaxEntries or MaxBytes
+// means no limit; a zero TTL keeps entries until they are evicted.
+type MemoryCacheOptions struct {
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// memoryCache is an LRU cache with per-entry expiry.
+type memoryCache struct {
+	mu    sync.Mutex
+	opts  MemoryCacheOptions
+	ll    *list.List
+	items map[string]*list.Element
+	bytes int64
+	now   func() time.Time
+}
+
+func NewMemoryCache(opts MemoryCacheOptions, codec Codec) CacheManager {
+	return newCodecCache(newMemoryCache(opts), codec, opts.TTL)
+}
+
+func newMemoryCache(opts MemoryCacheOptions) *memoryCache {
+	return &memoryCache{
+		opts:  opts,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (m *memoryCache) get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !m.now().Before(entry.expiresAt) {
+		m.removeElement(elem)
+		return nil, ErrCacheMiss
+	}
+	m.ll.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// set stores value for ttl. A value larger than MaxBytes is not cached at
+// all.
+func (m *memoryCache) set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		m.removeElement(elem)
+	}
+
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = m.now().Add(ttl)
+	}
+	if m.opts.MaxBytes > 0 && entry.size() > m.opts.MaxBytes {
+		return nil
+	}
+
+	m.items[key] = m.ll.PushFront(entry)
+	m.bytes += entry.size()
+
+	for m.overLimit() {
+		m.removeElement(m.ll.Back())
+	}
+	return nil
+}
+
+func (m *memoryCache) del(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := m.items[key]; ok {
+			m.removeElement(elem)
+		}
+	}
+	return nil
+}
+
+func (m *memoryCache) clear(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ll.Init()
+	m.items = make(map[string]*list.Element)
+	m.bytes = 0
+	return nil
+}
+
+func (m *memoryCache) overLimit() bool {
+	return (m.opts.MaxEntries > 0 && m.ll.Len() > m.opts.MaxEntries) ||
+		(m.opts.MaxBytes > 0 && m.bytes > m.opts.MaxBytes)
+}
+
+func (m *memoryCache) removeElement(elem *list.Element) {
+	entry := m.ll.Remove(elem).(*memoryEntry)
+	delete(m.items, entry.key)
+	m.bytes -= entry.size()
+}
@@ -0,0 +1,68 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := newMemoryCache(MemoryCacheOptions{MaxEntries: 2})
+
+	c.set(ctx, "a", []byte("1"), 0)
+	c.set(ctx, "b", []byte("2"), 0)
+	if _, err := c.get(ctx, "a"); err != nil {
+		t.Fatalf("expected a to be cached, got %v", err)
+	}
+	c.set(ctx, "c", []byte("3"), 0)
+
+	if _, err := c.get(ctx, "b"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected b to be evicted, got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.get(ctx, key); err != nil {
+			t.Fatalf("expected %s to be cached, got %v", key, err)
+		}
+	}
+}
+
+func TestMemoryCacheMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	// "k1" + "abcdef" is 8 bytes.
+	c := newMemoryCache(MemoryCacheOptions{MaxBytes: 16})
+
+	c.set(ctx, "k1", []byte("abcdef"), 0)
+	c.set(ctx, "k2", []byte("abcdef"), 0)
+	c.set(ctx, "k3", []byte("abcdef"), 0)
+	if _, err := c.get(ctx, "k1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected k1 to be evicted, got %v", err)
+	}
+	if c.bytes != 16 {
+		t.Fatalf("expected 16 bytes in use, got %d", c.bytes)
+	}
+
+	c.set(ctx, "big", []byte("this value does not fit"), 0)
+	if _, err := c.get(ctx, "big"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected an oversized value not to be cached, got %v", err)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := newMemoryCache(MemoryCacheOptions{})
+	c.now = func() time.Time { return now }
+
+	c.set(ctx, "short", []byte("x"), time.Minute)
+	c.set(ctx, "long", []byte("x"), time.Hour)
+
+	now = now.Add(2 * time.Minute)
+	if _, err := c.get(ctx, "short"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected short to expire, got %v", err)
+	}
+	if _, err := c.get(ctx, "long"); err != nil {
+		t.Fatalf("expected long to be cached, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Cache call results reported to a CacheObserver.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheOK    = "ok"
+	CacheError = "error"
+)
+
+// CacheObserver is told the outcome of every call made through a cache
+// returned by ObserveCache.
+type CacheObserver interface {
+	// ObserveCache records a call. operation is "get", "set" or "delete";
+	// result is CacheHit or CacheMiss for gets and CacheOK otherwise, or
+	// CacheError when the call failed.
+	ObserveCache(operation, result string)
+}
+
+// ObserveCache wraps cache so that observer sees every call.
+func ObserveCache(cache CacheManager, observer CacheObserver) CacheManager {
+	return &observedCache{cache, observer}
+}
+
+type observedCache struct {
+	cache    CacheManager
+	observer CacheObserver
+}
+
+func (c *observedCache) Get(ctx context.Context, key string, dest interface{}) error {
+	err := c.cache.Get(ctx, key, dest)
+	switch {
+	case err == nil:
+		c.observer.ObserveCache("get", CacheHit)
+	case errors.Is(err, ErrCacheMiss):
+		c.observer.ObserveCache("get", CacheMiss)
+	default:
+		c.observer.ObserveCache("get", CacheError)
+	}
+	return err
+}
+
+func (c *observedCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	err := c.cache.Set(ctx, key, value, ttl)
+	c.observer.ObserveCache("set", cacheResult(err))
+	return err
+}
+
+func (c *observedCache) Delete(ctx context.Context, keys ...string) error {
+	err := c.cache.Delete(ctx, keys...)
+	c.observer.ObserveCache("delete", cacheResult(err))
+	return err
+}
+
+func cacheResult(err error) string {
+	if err != nil {
+		return CacheError
+	}
+	return CacheOK
+}
@@ -3,20 +3,89 @@ package adapter
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/farisarmap/dot-backend-freelance/internal/entity"
 	"gorm.io/gorm"
 )
 
 type OrderRepository interface {
-	GetAll(ctx context.Context) ([]entity.Order, error)
+	List(ctx context.Context, filter OrderFilter, page PageQuery) ([]entity.Order, PageInfo, error)
+	Count(ctx context.Context, filter OrderFilter) (int64, error)
+	Export(ctx context.Context, filter OrderFilter, batchSize int, fn func([]OrderExportRow) error) error
 	GetByID(ctx context.Context, id uint) (entity.Order, error)
+	IDsByUser(ctx context.Context, userID uint) ([]uint, error)
 	Create(ctx context.Context, order *entity.Order) error
 	Update(ctx context.Context, order *entity.Order) error
 	Delete(ctx context.Context, order *entity.Order) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetStatusHistory(ctx context.Context, orderID uint) ([]entity.OrderStatusHistory, error)
 	Transaction(ctx context.Context, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error
 }
 
+// OrderSortFields lists the fields GET /orders can be sorted by.
+var OrderSortFields = map[string]string{
+	"id":         "id",
+	"order_name": "order_name",
+	"user_id":    "user_id",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+type OrderFilter struct {
+	OrderName   string
+	UserID      *uint
+	Status      entity.OrderStatus
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        []SortField
+
+	IncludeDeleted bool
+}
+
+// apply adds the conditions of f to a query on orders. Columns are qualified
+// so that the query may join other tables.
+func (f OrderFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	if f.OrderName != "" {
+		query = query.Where("orders.order_name ILIKE ?", containsPattern(f.OrderName))
+	}
+	if f.UserID != nil {
+		query = query.Where("orders.user_id = ?", *f.UserID)
+	}
+	if f.Status != "" {
+		query = query.Where("orders.status = ?", f.Status)
+	}
+	if f.CreatedFrom != nil {
+		query = query.Where("orders.created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		query = query.Where("orders.created_at <= ?", *f.CreatedTo)
+	}
+	return query
+}
+
+func orderColumnValue(o entity.Order, column string) interface{} {
+	switch column {
+	case "order_name":
+		return o.OrderName
+	case "user_id":
+		return o.UserID
+	case "status":
+		return o.Status
+	case "created_at":
+		return o.CreatedAt
+	case "updated_at":
+		return o.UpdatedAt
+	default:
+		return o.ID
+	}
+}
+
 type orderRepository struct {
 	db *gorm.DB
 }
@@ -25,34 +94,56 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db}
 }
 
-func (r *orderRepository) GetAll(ctx context.Context) ([]entity.Order, error) {
-	var orders []entity.Order
-	if err := r.db.WithContext(ctx).Preload("User").Find(&orders).Error; err != nil {
-		return nil, err
-	}
-	return orders, nil
+func (r *orderRepository) List(ctx context.Context, filter OrderFilter, page PageQuery) ([]entity.Order, PageInfo, error) {
+	query := filter.apply(r.db.WithContext(ctx).Model(&entity.Order{}))
+
+	return paginate(query, page, filter.Sort, orderColumnValue, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("User").Preload("Items")
+	})
 }
 
 func (r *orderRepository) GetByID(ctx context.Context, id uint) (entity.Order, error) {
 	var order entity.Order
-	if err := r.db.WithContext(ctx).Preload("User").First(&order, id).Error; err != nil {
-		return order, err
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Items").First(&order, id).Error; err != nil {
+		return order, NotFound(err, entity.ErrOrderNotFound)
 	}
 	return order, nil
 }
 
+// IDsByUser returns the ids of all orders of userID, soft deleted included.
+func (r *orderRepository) IDsByUser(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Unscoped().Model(&entity.Order{}).Where("user_id = ?", userID).Pluck("id", &ids).Error
+	return ids, err
+}
+
 func (r *orderRepository) Create(ctx context.Context, order *entity.Order) error {
-	return r.db.WithContext(ctx).Create(order).Error
+	return translateError(r.db.WithContext(ctx).Create(order).Error)
 }
 
 func (r *orderRepository) Update(ctx context.Context, order *entity.Order) error {
-	return r.db.WithContext(ctx).Save(order).Error
+	return translateError(r.db.WithContext(ctx).Save(order).Error)
 }
 
 func (r *orderRepository) Delete(ctx context.Context, order *entity.Order) error {
-	return r.db.WithContext(ctx).Delete(order).Error
+	return translateError(r.db.WithContext(ctx).Delete(order).Error)
+}
+
+func (r *orderRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Delete(&entity.Order{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *orderRepository) GetStatusHistory(ctx context.Context, orderID uint) ([]entity.OrderStatusHistory, error) {
+	history := []entity.OrderStatusHistory{}
+	if err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
 func (r *orderRepository) Transaction(ctx context.Context, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
-	return r.db.WithContext(ctx).Transaction(fc, opts...)
+	return translateError(r.db.WithContext(ctx).Transaction(fc, opts...))
 }
@@ -0,0 +1,95 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderExportRow is an order as it is exported: flat, with the name and
+// email of its owner and its totals instead of its items.
+type OrderExportRow struct {
+	ID         uint       `json:"id"`
+	OrderName  string     `json:"order_name"`
+	Status     string     `json:"status"`
+	UserID     uint       `json:"user_id"`
+	UserName   string     `json:"user_name"`
+	UserEmail  string     `json:"user_email"`
+	Currency   string     `json:"currency"`
+	TaxRateBPS int64      `json:"tax_rate_bps"`
+	ItemCount  int64      `json:"item_count"`
+	Subtotal   int64      `json:"subtotal"`
+	Tax        int64      `gorm:"-" json:"tax"`
+	Total      int64      `gorm:"-" json:"total"`
+	Version    uint       `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+}
+
+// exportCursor names the cursor Export reads orders through.
+const exportCursor = "order_export"
+
+// Count returns the number of orders matching filter.
+func (r *orderRepository) Count(ctx context.Context, filter OrderFilter) (int64, error) {
+	var count int64
+	err := filter.apply(r.db.WithContext(ctx).Model(&entity.Order{})).Count(&count).Error
+	return count, err
+}
+
+// Export passes the orders matching filter, in the order of filter.Sort, to
+// fn batchSize rows at a time. The rows are read through a server-side
+// cursor in one read-only transaction, so the export sees a single snapshot
+// and never holds more than a batch in memory.
+func (r *orderRepository) Export(ctx context.Context, filter OrderFilter, batchSize int, fn func([]OrderExportRow) error) error {
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		query := filter.apply(tx.Session(&gorm.Session{DryRun: true}).Model(&entity.Order{})).
+			Select(`orders.id, orders.order_name, orders.status, orders.user_id,
+				users.name AS user_name, users.email AS user_email,
+				orders.currency, orders.tax_rate_bps,
+				items.item_count, items.subtotal,
+				orders.version, orders.created_at, orders.updated_at, orders.deleted_at`).
+			Joins("LEFT JOIN users ON users.id = orders.user_id").
+			Joins(`CROSS JOIN LATERAL (
+				SELECT COUNT(*) AS item_count, COALESCE(SUM(order_items.quantity * order_items.unit_price), 0)::BIGINT AS subtotal
+				FROM order_items WHERE order_items.order_id = orders.id
+			) items`)
+		for _, key := range sortKey(filter.Sort) {
+			query = query.Order(clause.OrderByColumn{Column: clause.Column{Table: "orders", Name: key.Column}, Desc: key.Desc})
+		}
+		stmt := query.Find(&[]OrderExportRow{}).Statement
+
+		// The statement already holds the dialect's placeholders, so it is
+		// sent as is rather than through gorm.
+		declare := fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", exportCursor, stmt.SQL.String())
+		if _, err := tx.Statement.ConnPool.ExecContext(ctx, declare, stmt.Vars...); err != nil {
+			return err
+		}
+
+		fetch := fmt.Sprintf("FETCH %d FROM %s", batchSize, exportCursor)
+		for {
+			var rows []OrderExportRow
+			if err := tx.Raw(fetch).Scan(&rows).Error; err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+			for i := range rows {
+				totals, err := entity.TotalsFromSubtotal(rows[i].Currency, rows[i].Subtotal, rows[i].TaxRateBPS)
+				if err != nil {
+					return err
+				}
+				rows[i].Tax, rows[i].Total = totals.Tax, totals.Total
+			}
+			if err := fn(rows); err != nil {
+				return err
+			}
+		}
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
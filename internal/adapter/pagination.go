@@ -0,0 +1,255 @@
+package adapter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = apperror.BadRequest("invalid_cursor", "invalid cursor")
+	ErrInvalidSort   = apperror.BadRequest("invalid_sort", "invalid sort")
+)
+
+type PageQuery struct {
+	Limit     int
+	Offset    int
+	Cursor    string
+	WithTotal bool
+}
+
+// Normalize clamps the limit and drops the offset when a cursor is given.
+func (p PageQuery) Normalize() PageQuery {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.Offset < 0 || p.Cursor != "" {
+		p.Offset = 0
+	}
+	return p
+}
+
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+var defaultSort = []SortField{{Column: "created_at", Desc: true}}
+
+// ParseSort parses a comma separated sort expression such as
+// "-created_at,order_name". Only the fields listed in allowed are accepted;
+// allowed maps the public field name to its column.
+func ParseSort(raw string, allowed map[string]string) ([]SortField, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		column, ok := allowed[name]
+		if !ok {
+			return nil, apperror.BadRequest(ErrInvalidSort.Code, fmt.Sprintf("invalid sort: unknown field %q", name))
+		}
+		if seen[column] {
+			return nil, apperror.BadRequest(ErrInvalidSort.Code, fmt.Sprintf("invalid sort: duplicate field %q", name))
+		}
+		seen[column] = true
+		fields = append(fields, SortField{Column: column, Desc: desc})
+	}
+	return fields, nil
+}
+
+// sortKey returns the sort with id appended as a tie-breaker so that the
+// ordering is total, which keyset pagination relies on.
+func sortKey(sort []SortField) []SortField {
+	if len(sort) == 0 {
+		sort = defaultSort
+	}
+	keys := make([]SortField, 0, len(sort)+1)
+	for _, f := range sort {
+		if f.Column == "id" {
+			return append(keys, f)
+		}
+		keys = append(keys, f)
+	}
+	return append(keys, SortField{Column: "id", Desc: sort[len(sort)-1].Desc})
+}
+
+func sortSignature(keys []SortField) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if k.Desc {
+			parts[i] = "-" + k.Column
+		} else {
+			parts[i] = k.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+type pageCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+func encodeCursor(keys []SortField, values []interface{}) (string, error) {
+	c := pageCursor{Sort: sortSignature(keys)}
+	for _, v := range values {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		c.Values = append(c.Values, raw)
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor restores the cursor values using the Go types of zero, which
+// holds the column values of an empty row in sort key order.
+func decodeCursor(s string, keys []SortField, zero []interface{}) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, rawValue := range c.Values {
+		ptr := reflect.New(reflect.TypeOf(zero[i]))
+		if err := json.Unmarshal(rawValue, ptr.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = ptr.Elem().Interface()
+	}
+	return values, nil
+}
+
+// keysetCondition builds "rows strictly after values" for the given keys, e.g.
+// for (a DESC, id DESC): a < ? OR (a = ? AND id < ?).
+func keysetCondition(db *gorm.DB, keys []SortField, values []interface{}) clause.Expr {
+	var (
+		ors  []string
+		args []interface{}
+	)
+	for i, key := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, db.Statement.Quote(keys[j].Column)+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.Desc {
+			op = "<"
+		}
+		ands = append(ands, db.Statement.Quote(key.Column)+" "+op+" ?")
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return clause.Expr{SQL: "(" + strings.Join(ors, " OR ") + ")", Vars: args}
+}
+
+// paginate runs query with the given sort, using keyset pagination when a
+// cursor is given and offset pagination otherwise. valueOf returns the value
+// of a sortable column for a row. findScopes are applied to the page query
+// only, not to the total count.
+func paginate[T any](
+	query *gorm.DB,
+	page PageQuery,
+	sort []SortField,
+	valueOf func(row T, column string) interface{},
+	findScopes ...func(*gorm.DB) *gorm.DB,
+) ([]T, PageInfo, error) {
+	page = page.Normalize()
+	info := PageInfo{Limit: page.Limit, Offset: page.Offset}
+	keys := sortKey(sort)
+
+	rowValues := func(row T) []interface{} {
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = valueOf(row, key.Column)
+		}
+		return values
+	}
+
+	if page.WithTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, info, err
+		}
+		info.Total = &total
+	}
+
+	if page.Cursor != "" {
+		var zero T
+		values, err := decodeCursor(page.Cursor, keys, rowValues(zero))
+		if err != nil {
+			return nil, info, err
+		}
+		query = query.Where(keysetCondition(query, keys, values))
+	} else if page.Offset > 0 {
+		query = query.Offset(page.Offset)
+	}
+
+	for _, key := range keys {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc})
+	}
+
+	var items []T
+	if err := query.Scopes(findScopes...).Limit(page.Limit + 1).Find(&items).Error; err != nil {
+		return nil, info, err
+	}
+
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+		cursor, err := encodeCursor(keys, rowValues(items[page.Limit-1]))
+		if err != nil {
+			return nil, info, err
+		}
+		info.NextCursor = cursor
+	}
+
+	return items, info, nil
+}
+
+// containsPattern turns user input into an ILIKE pattern matching it as a
+// literal substring.
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
@@ -0,0 +1,247 @@
+package adapter
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseSort(t *testing.T) {
+	testCases := []struct {
+		name    string
+		raw     string
+		want    []SortField
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			raw:  "",
+			want: nil,
+		},
+		{
+			name: "Multiple fields",
+			raw:  "-created_at,order_name",
+			want: []SortField{{Column: "created_at", Desc: true}, {Column: "order_name"}},
+		},
+		{
+			name:    "Unknown field",
+			raw:     "password",
+			wantErr: true,
+		},
+		{
+			name:    "Duplicate field",
+			raw:     "id,-id",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseSort(tc.raw, OrderSortFields)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidSort) {
+					t.Fatalf("expected ErrInvalidSort, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSortKeyAddsIDTieBreaker(t *testing.T) {
+	keys := sortKey([]SortField{{Column: "order_name"}})
+	if len(keys) != 2 || keys[1] != (SortField{Column: "id"}) {
+		t.Fatalf("expected id tie-breaker, got %v", keys)
+	}
+
+	keys = sortKey(nil)
+	if sortSignature(keys) != "-created_at,-id" {
+		t.Fatalf("unexpected default sort: %s", sortSignature(keys))
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys := sortKey([]SortField{{Column: "created_at", Desc: true}})
+	createdAt := time.Date(2024, 12, 23, 12, 0, 0, 123000, time.UTC)
+
+	cursor, err := encodeCursor(keys, []interface{}{createdAt, uint(42)})
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	values, err := decodeCursor(cursor, keys, []interface{}{time.Time{}, uint(0)})
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if got, ok := values[0].(time.Time); !ok || !got.Equal(createdAt) {
+		t.Fatalf("unexpected created_at: %v", values[0])
+	}
+	if got, ok := values[1].(uint); !ok || got != 42 {
+		t.Fatalf("unexpected id: %v", values[1])
+	}
+
+	otherKeys := sortKey([]SortField{{Column: "order_name"}})
+	if _, err := decodeCursor(cursor, otherKeys, []interface{}{"", uint(0)}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for a different sort, got %v", err)
+	}
+}
+
+func TestPageQueryNormalize(t *testing.T) {
+	testCases := []struct {
+		name string
+		page PageQuery
+		want PageQuery
+	}{
+		{"Defaults", PageQuery{}, PageQuery{Limit: DefaultPageLimit}},
+		{"Limit capped", PageQuery{Limit: 1000, Offset: 40}, PageQuery{Limit: MaxPageLimit, Offset: 40}},
+		{"Negative offset", PageQuery{Limit: 10, Offset: -5}, PageQuery{Limit: 10}},
+		{"Cursor drops offset", PageQuery{Limit: 10, Offset: 40, Cursor: "c"}, PageQuery{Limit: 10, Cursor: "c"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.page.Normalize(); got != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	keys := sortKey(nil)
+	zero := []interface{}{time.Time{}, uint(0)}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	testCases := []struct {
+		name   string
+		cursor string
+	}{
+		{"Not base64", "not a cursor!"},
+		{"Not JSON", encode("{")},
+		{"Too few values", encode(`{"s":"-created_at,-id","v":["2024-12-23T12:00:00Z"]}`)},
+		{"Wrong value type", encode(`{"s":"-created_at,-id","v":["2024-12-23T12:00:00Z","forty-two"]}`)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodeCursor(tc.cursor, keys, zero); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("expected ErrInvalidCursor, got %v", err)
+			}
+		})
+	}
+}
+
+// dryRunDB builds statements without executing them.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open dry run db: %v", err)
+	}
+	return db
+}
+
+func TestKeysetCondition(t *testing.T) {
+	db := dryRunDB(t)
+	createdAt := time.Date(2024, 12, 23, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		keys     []SortField
+		values   []interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "Descending",
+			keys:     sortKey(nil),
+			values:   []interface{}{createdAt, uint(42)},
+			wantSQL:  `(("created_at" < ?) OR ("created_at" = ? AND "id" < ?))`,
+			wantVars: []interface{}{createdAt, createdAt, uint(42)},
+		},
+		{
+			name:     "Ascending",
+			keys:     sortKey([]SortField{{Column: "order_name"}}),
+			values:   []interface{}{"Logo", uint(7)},
+			wantSQL:  `(("order_name" > ?) OR ("order_name" = ? AND "id" > ?))`,
+			wantVars: []interface{}{"Logo", "Logo", uint(7)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr := keysetCondition(db, tc.keys, tc.values)
+			if expr.SQL != tc.wantSQL {
+				t.Errorf("expected %s, got %s", tc.wantSQL, expr.SQL)
+			}
+			if !reflect.DeepEqual(expr.Vars, tc.wantVars) {
+				t.Errorf("expected %v, got %v", tc.wantVars, expr.Vars)
+			}
+		})
+	}
+}
+
+func TestPaginateWithCursor(t *testing.T) {
+	type row struct {
+		ID        uint
+		CreatedAt time.Time
+	}
+	db := dryRunDB(t)
+	var sql string
+	err := db.Callback().Query().After("gorm:query").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	keys := sortKey(nil)
+	cursor, err := encodeCursor(keys, []interface{}{time.Date(2024, 12, 23, 12, 0, 0, 0, time.UTC), uint(42)})
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	valueOf := func(r row, column string) interface{} {
+		if column == "id" {
+			return r.ID
+		}
+		return r.CreatedAt
+	}
+
+	_, info, err := paginate(db.Table("orders"), PageQuery{Limit: 10, Offset: 30, Cursor: cursor}, nil, valueOf)
+	if err != nil {
+		t.Fatalf("paginate: %v", err)
+	}
+	if info.Offset != 0 || info.NextCursor != "" {
+		t.Errorf("unexpected page info %+v", info)
+	}
+	for _, want := range []string{`WHERE (("created_at" < $1) OR ("created_at" = $2 AND "id" < $3))`, `ORDER BY "created_at" DESC,"id" DESC`, "LIMIT $4"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected %s in %s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "OFFSET") {
+		t.Errorf("expected no offset with a cursor, got %s", sql)
+	}
+}
@@ -2,48 +2,89 @@ package adapter
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-type CacheManager interface {
-	Get(key string) (string, error)
-	Set(key string, value interface{}) error
-	Delete(key string) error
-}
+// clearBatch is the number of keys scanned and unlinked at a time by clear.
+const clearBatch = 500
 
+// redisCache stores every key under prefix, so that clearing the cache
+// leaves the other keys of the Redis database alone.
 type redisCache struct {
-	client  *redis.Client
-	ctx     context.Context
-	timeout time.Duration
+	client *redis.Client
+	prefix string
 }
 
-func NewRedisCache(client *redis.Client, ttl time.Duration) CacheManager {
-	return &redisCache{
-		client:  client,
-		ctx:     context.Background(),
-		timeout: ttl,
-	}
+// NewRedisCache returns a cache backed by Redis that stores its keys under
+// prefix. When breaker is not nil, calls go through it so that an
+// unreachable Redis is bypassed.
+func NewRedisCache(client *redis.Client, prefix string, ttl time.Duration, codec Codec, breaker *CircuitBreaker) CacheManager {
+	return newCodecCache(guard(&redisCache{client: client, prefix: prefix}, breaker), codec, ttl)
+}
+
+func (r *redisCache) key(key string) string {
+	return r.prefix + key
 }
 
-func (r *redisCache) Get(key string) (string, error) {
-	val, err := r.client.Get(r.ctx, key).Result()
+func (r *redisCache) get(ctx context.Context, key string) ([]byte, error) {
+	val, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	return val, nil
 }
 
-func (r *redisCache) Set(key string, value interface{}) error {
-	bytes, err := json.Marshal(value)
-	if err != nil {
+func (r *redisCache) set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.key(key), value, ttl).Err()
+}
+
+func (r *redisCache) del(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.key(key)
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+// clear unlinks every key under the prefix, a batch at a time.
+func (r *redisCache) clear(ctx context.Context) error {
+	iter := r.client.Scan(ctx, 0, matchPrefix(r.prefix), clearBatch).Iterator()
+	batch := make([]string, 0, clearBatch)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == clearBatch {
+			if err := r.client.Unlink(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
 		return err
 	}
-	return r.client.Set(r.ctx, key, bytes, r.timeout).Err()
+	if len(batch) == 0 {
+		return nil
+	}
+	return r.client.Unlink(ctx, batch...).Err()
 }
 
-func (r *redisCache) Delete(key string) error {
-	return r.client.Del(r.ctx, key).Err()
+// matchPrefix returns the SCAN pattern matching the keys that start with
+// prefix, with the glob characters of prefix escaped.
+func matchPrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('*')
+	return b.String()
 }
@@ -0,0 +1,16 @@
+package adapter
+
+import "testing"
+
+func TestMatchPrefix(t *testing.T) {
+	testCases := map[string]string{
+		"cache:":      "cache:*",
+		"app[1]:*?\\": `app\[1\]:\*\?\\*`,
+		"":            "*",
+	}
+	for prefix, want := range testCases {
+		if got := matchPrefix(prefix); got != want {
+			t.Errorf("matchPrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository stores the refresh tokens that may still be
+// exchanged. A token is active while its row exists, so deleting the row
+// revokes it.
+//
+// It is backed by Postgres rather than CacheManager: the circuit breaker
+// skips writes and turns reads into misses while Redis is down, and Redis may
+// evict keys under memory pressure, so tokens and revocations kept there
+// could be lost without an error.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, id string, userID uint, expiresAt time.Time) error
+	// Consume deletes the active token id and returns the user it was issued
+	// to. It reports false when the token is unknown, already consumed or
+	// expired; of concurrent calls with the same id only one succeeds.
+	Consume(ctx context.Context, id string, now time.Time) (uint, bool, error)
+	Delete(ctx context.Context, id string) error
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, id string, userID uint, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Exec(
+		`INSERT INTO refresh_tokens (id, user_id, expires_at) VALUES (?, ?, ?)`,
+		id, userID, expiresAt,
+	).Error
+}
+
+func (r *refreshTokenRepository) Consume(ctx context.Context, id string, now time.Time) (uint, bool, error) {
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Raw(
+		`DELETE FROM refresh_tokens WHERE id = ? AND expires_at > ? RETURNING user_id`,
+		id, now,
+	).Scan(&userIDs).Error
+	if err != nil || len(userIDs) == 0 {
+		return 0, false, err
+	}
+	return userIDs[0], true, nil
+}
+
+func (r *refreshTokenRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Exec(`DELETE FROM refresh_tokens WHERE id = ?`, id).Error
+}
+
+func (r *refreshTokenRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM refresh_tokens WHERE expires_at < ?`, now)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,108 @@
+package adapter
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/gorm"
+)
+
+// ts_headline marks the matches with control characters, which are swapped
+// for <mark> tags once the rest of the snippet has been HTML-escaped. The
+// same characters are removed from the text beforehand, so that stored
+// values cannot forge a mark.
+const (
+	headlineStart   = "\x01"
+	headlineStop    = "\x02"
+	headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", MaxFragments=2, MinWords=3, MaxWords=12"
+)
+
+type SearchRepository interface {
+	SearchUsers(ctx context.Context, terms []string, limit int) ([]entity.SearchHit, error)
+	SearchOrders(ctx context.Context, terms []string, userID *uint, limit int) ([]entity.SearchHit, error)
+}
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{db}
+}
+
+func (r *searchRepository) SearchUsers(ctx context.Context, terms []string, limit int) ([]entity.SearchHit, error) {
+	var hits []entity.SearchHit
+	err := r.db.WithContext(ctx).
+		Table("users, to_tsquery('simple', ?) AS q", prefixTSQuery(terms)).
+		Select(`? AS type, users.id, users.name AS title,
+			ts_headline('simple', translate(users.name || ' ' || users.email, chr(1) || chr(2), ''), q, ?) AS snippet,
+			ts_rank(users.search_vector, q) AS rank`, entity.SearchTypeUser, headlineOptions).
+		Where("users.search_vector @@ q AND users.deleted_at IS NULL").
+		Order("rank DESC, users.id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return highlightHits(hits), nil
+}
+
+// SearchOrders searches all orders, or only the orders of userID when given.
+func (r *searchRepository) SearchOrders(ctx context.Context, terms []string, userID *uint, limit int) ([]entity.SearchHit, error) {
+	query := r.db.WithContext(ctx)
+	if userID != nil {
+		query = query.Where("orders.user_id = ?", *userID)
+	}
+
+	var hits []entity.SearchHit
+	err := query.
+		Table("orders, to_tsquery('simple', ?) AS q", prefixTSQuery(terms)).
+		Select(`? AS type, orders.id, orders.order_name AS title,
+			ts_headline('simple', translate(orders.order_name, chr(1) || chr(2), ''), q, ?) AS snippet,
+			ts_rank(orders.search_vector, q) AS rank`, entity.SearchTypeOrder, headlineOptions).
+		Where("orders.search_vector @@ q AND orders.deleted_at IS NULL").
+		Order("rank DESC, orders.id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return highlightHits(hits), nil
+}
+
+// SearchTerms splits free text into the lowercase words a tsquery is built
+// from, dropping everything that is not a letter or a digit.
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixTSQuery matches every term as a prefix, so "jo exa" finds
+// "john@example.com". terms must come from SearchTerms.
+func prefixTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// highlightHits turns the snippets of hits into HTML. Names, emails and
+// order names are user input, so they are escaped and only the <mark> tags
+// around the matches are left as markup.
+func highlightHits(hits []entity.SearchHit) []entity.SearchHit {
+	for i := range hits {
+		hits[i].Snippet = highlight(hits[i].Snippet)
+	}
+	return hits
+}
+
+var headlineMarks = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+func highlight(snippet string) string {
+	return headlineMarks.Replace(html.EscapeString(snippet))
+}
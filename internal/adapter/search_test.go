@@ -0,0 +1,95 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestSearchTerms(t *testing.T) {
+	testCases := []struct {
+		text string
+		want []string
+	}{
+		{"John Doe", []string{"john", "doe"}},
+		{"jo@exa.com", []string{"jo", "exa", "com"}},
+		{"logo & (web | !app):*", []string{"logo", "web", "app"}},
+		{"Ünïcode 2024", []string{"ünïcode", "2024"}},
+		{"  -- ", nil},
+	}
+
+	for _, tc := range testCases {
+		got := SearchTerms(tc.text)
+		if len(got) != len(tc.want) || len(got) > 0 && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestPrefixTSQuery(t *testing.T) {
+	if got := prefixTSQuery(SearchTerms("jo exa")); got != "jo:* & exa:*" {
+		t.Errorf("got %q", got)
+	}
+	if got := prefixTSQuery(SearchTerms("it's a:b")); got != "it:* & s:* & a:* & b:*" {
+		t.Errorf("expected tsquery operators to be dropped, got %q", got)
+	}
+}
+
+func TestHighlightEscapesSnippet(t *testing.T) {
+	snippet := "<script>alert(1)</script> \x01Alice\x02 \"a&b\"@example.com"
+	want := "&lt;script&gt;alert(1)&lt;/script&gt; <mark>Alice</mark> &#34;a&amp;b&#34;@example.com"
+	if got := highlight(snippet); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// searchStatement runs search against a dry run database and returns the
+// SQL it would have sent along with its values. Scanning fails in a dry run,
+// so the error of search is ignored.
+func searchStatement(t *testing.T, search func(SearchRepository)) (string, []interface{}) {
+	t.Helper()
+	db := dryRunDB(t)
+
+	var sql string
+	var vars []interface{}
+	err := db.Callback().Row().After("gorm:row").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	search(NewSearchRepository(db))
+	if sql == "" {
+		t.Fatal("no query was run")
+	}
+	return sql, vars
+}
+
+func TestSearchQueries(t *testing.T) {
+	terms := SearchTerms("jo exa")
+	owner := uint(7)
+
+	for name, search := range map[string]func(SearchRepository){
+		"users":  func(r SearchRepository) { r.SearchUsers(context.Background(), terms, 20) },
+		"orders": func(r SearchRepository) { r.SearchOrders(context.Background(), terms, &owner, 20) },
+	} {
+		sql, vars := searchStatement(t, search)
+		if !strings.Contains(sql, "search_vector @@ q") || !strings.Contains(sql, "deleted_at IS NULL") {
+			t.Errorf("%s: expected matching rows that are not deleted, got %s", name, sql)
+		}
+		if !strings.Contains(sql, "translate(") {
+			t.Errorf("%s: expected the headline text to be stripped of marks, got %s", name, sql)
+		}
+		if !slices.Contains(vars, any("jo:* & exa:*")) {
+			t.Errorf("%s: expected the prefix tsquery among the values, got %v", name, vars)
+		}
+		if name == "orders" && !strings.Contains(sql, "orders.user_id = $") {
+			t.Errorf("orders: expected the owner filter, got %s", sql)
+		}
+	}
+}
@@ -3,20 +3,61 @@ package adapter
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/farisarmap/dot-backend-freelance/internal/entity"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	GetAll(ctx context.Context) ([]entity.User, error)
+	List(ctx context.Context, filter UserFilter, page PageQuery) ([]entity.User, PageInfo, error)
 	GetByID(ctx context.Context, id uint) (entity.User, error)
+	GetByEmail(ctx context.Context, email string) (entity.User, error)
+	ExistingIDs(ctx context.Context, ids []uint) (map[uint]bool, error)
+	IDsByEmail(ctx context.Context, emails []string) (map[string]uint, error)
 	Create(ctx context.Context, user *entity.User) error
+	CreateBatch(ctx context.Context, users []entity.User) error
 	Update(ctx context.Context, user *entity.User) error
 	Delete(ctx context.Context, user *entity.User) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 	Transaction(ctx context.Context, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error
 }
 
+// UserSortFields lists the fields GET /users can be sorted by.
+var UserSortFields = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+type UserFilter struct {
+	Name        string
+	Email       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        []SortField
+
+	IncludeDeleted bool
+}
+
+func userColumnValue(u entity.User, column string) interface{} {
+	switch column {
+	case "name":
+		return u.Name
+	case "email":
+		return u.Email
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	default:
+		return u.ID
+	}
+}
+
 type userRepository struct {
 	db *gorm.DB
 }
@@ -25,34 +66,113 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db}
 }
 
-func (r *userRepository) GetAll(ctx context.Context) ([]entity.User, error) {
-	var users []entity.User
-	if err := r.db.WithContext(ctx).Preload("Orders").Find(&users).Error; err != nil {
-		return nil, err
+func (r *userRepository) List(ctx context.Context, filter UserFilter, page PageQuery) ([]entity.User, PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&entity.User{})
+
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
 	}
-	return users, nil
+
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", containsPattern(filter.Name))
+	}
+	if filter.Email != "" {
+		query = query.Where("LOWER(email) = LOWER(?)", filter.Email)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *filter.CreatedTo)
+	}
+
+	return paginate(query, page, filter.Sort, userColumnValue, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Orders.Items")
+	})
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id uint) (entity.User, error) {
 	var user entity.User
-	if err := r.db.WithContext(ctx).Preload("Orders").First(&user, id).Error; err != nil {
-		return user, err
+	if err := r.db.WithContext(ctx).Preload("Orders.Items").First(&user, id).Error; err != nil {
+		return user, NotFound(err, entity.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (entity.User, error) {
+	var user entity.User
+	if err := r.db.WithContext(ctx).Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return user, NotFound(err, entity.ErrUserNotFound)
 	}
 	return user, nil
 }
 
+// ExistingIDs reports which of ids belong to users that are not deleted.
+func (r *userRepository) ExistingIDs(ctx context.Context, ids []uint) (map[uint]bool, error) {
+	existing := make(map[uint]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	var found []uint
+	if err := r.db.WithContext(ctx).Model(&entity.User{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// IDsByEmail returns the ids of the users that are not deleted, keyed by
+// their lower-cased email. Emails match case-insensitively like GetByEmail.
+func (r *userRepository) IDsByEmail(ctx context.Context, emails []string) (map[string]uint, error) {
+	ids := make(map[string]uint, len(emails))
+	if len(emails) == 0 {
+		return ids, nil
+	}
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+
+	var users []entity.User
+	if err := r.db.WithContext(ctx).Select("id", "email").Where("LOWER(email) IN ?", lowered).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		ids[strings.ToLower(user.Email)] = user.ID
+	}
+	return ids, nil
+}
+
 func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return translateError(r.db.WithContext(ctx).Create(user).Error)
+}
+
+// CreateBatch inserts users with a single statement and sets their ids.
+func (r *userRepository) CreateBatch(ctx context.Context, users []entity.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	return translateError(r.db.WithContext(ctx).CreateInBatches(users, len(users)).Error)
 }
 
 func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	return translateError(r.db.WithContext(ctx).Save(user).Error)
 }
 
 func (r *userRepository) Delete(ctx context.Context, user *entity.User) error {
-	return r.db.WithContext(ctx).Delete(user).Error
+	return translateError(r.db.WithContext(ctx).Delete(user).Error)
+}
+
+func (r *userRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id)").
+		Delete(&entity.User{})
+	return result.RowsAffected, result.Error
 }
 
 func (r *userRepository) Transaction(ctx context.Context, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
-	return r.db.WithContext(ctx).Transaction(fc, opts...)
+	return translateError(r.db.WithContext(ctx).Transaction(fc, opts...))
 }
@@ -0,0 +1,17 @@
+package api
+
+type Register struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+	// bcrypt ignores everything after the first 72 bytes.
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type Login struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
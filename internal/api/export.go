@@ -0,0 +1,8 @@
+package api
+
+// OrderExportQuery is the query of GET /orders/export and POST
+// /orders/exports. Format defaults to csv.
+type OrderExportQuery struct {
+	OrderFilterQuery
+	Format string `query:"format" validate:"omitempty,oneof=csv ndjson xlsx"`
+}
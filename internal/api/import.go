@@ -0,0 +1,25 @@
+package api
+
+// Import types name the records of an import file.
+const (
+	ImportTypeUsers  = "users"
+	ImportTypeOrders = "orders"
+)
+
+// ImportQuery selects what POST /imports reads. Format defaults to the one
+// of the Content-Type header.
+type ImportQuery struct {
+	Type   string `query:"type" validate:"required,oneof=users orders"`
+	Format string `query:"format" validate:"omitempty,oneof=csv ndjson"`
+}
+
+// ImportOrder is a row of an order import. The user is resolved by email;
+// the other fields are validated like CreateOrder. In CSV files items is a
+// JSON array.
+type ImportOrder struct {
+	UserEmail  string             `json:"user_email" validate:"required,email"`
+	OrderName  string             `json:"order_name"`
+	Currency   string             `json:"currency"`
+	TaxRateBPS int64              `json:"tax_rate_bps"`
+	Items      []OrderItemRequest `json:"items"`
+}
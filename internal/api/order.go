@@ -1,13 +1,30 @@
 package api
 
+import "time"
+
 type CreateOrder struct {
-	OrderName string `json:"order_name" validate:"required,min=3,max=100"`
-	UserID    uint   `json:"user_id" validate:"required"`
+	OrderName  string             `json:"order_name" validate:"required,min=3,max=100"`
+	UserID     uint               `json:"user_id" validate:"required"`
+	Currency   string             `json:"currency" validate:"omitempty,iso4217"`
+	TaxRateBPS int64              `json:"tax_rate_bps" validate:"min=0,max=10000"`
+	Items      []OrderItemRequest `json:"items" validate:"omitempty,max=100,dive"`
 }
 
 type PartiallyUpdateOrder struct {
-	OrderName *string `json:"order_name" validate:"omitempty,min=3,max=100"`
-	UserID    *uint   `json:"user_id"`
+	OrderName  *string             `json:"order_name" validate:"omitempty,min=3,max=100"`
+	UserID     *uint               `json:"user_id"`
+	Currency   *string             `json:"currency" validate:"omitempty,iso4217"`
+	TaxRateBPS *int64              `json:"tax_rate_bps" validate:"omitempty,min=0,max=10000"`
+	Items      *[]OrderItemRequest `json:"items" validate:"omitempty,max=100,dive"`
+}
+
+// OrderItemRequest is a line of an order. UnitPrice is in the minor unit of the
+// currency (e.g. cents). Currency defaults to the order currency.
+type OrderItemRequest struct {
+	Description string `json:"description" validate:"required,max=255"`
+	Quantity    int64  `json:"quantity" validate:"required,min=1,max=1000000"`
+	UnitPrice   int64  `json:"unit_price" validate:"min=0"`
+	Currency    string `json:"currency" validate:"omitempty,iso4217"`
 }
 
 type CreateUserAndOrderRequest struct {
@@ -23,3 +40,64 @@ type CreateUserRequest struct {
 type CreateOrderRequest struct {
 	OrderName string `json:"order_name" validate:"required"`
 }
+
+type OrderListQuery struct {
+	PaginationQuery
+	OrderFilterQuery
+}
+
+// OrderFilterQuery holds the filters shared by GET /orders and the order
+// exports.
+type OrderFilterQuery struct {
+	OrderName   string     `query:"order_name" validate:"omitempty,max=100"`
+	UserID      *uint      `query:"user_id" validate:"omitempty,min=1"`
+	Status      string     `query:"status" validate:"omitempty,oneof=draft submitted accepted in_progress delivered completed cancelled disputed"`
+	CreatedFrom *time.Time `query:"created_from"`
+	CreatedTo   *time.Time `query:"created_to"`
+	Sort        string     `query:"sort"`
+
+	IncludeDeleted bool `query:"include_deleted"`
+}
+
+// Bulk modes: in the atomic mode (the default) nothing is written unless
+// every item succeeds; in the best_effort mode the valid items are written
+// and the others reported.
+const (
+	BulkModeAtomic     = "atomic"
+	BulkModeBestEffort = "best_effort"
+)
+
+// BulkCreateOrders is the body of POST /orders/bulk. Items are validated one
+// by one, so they carry no dive tag.
+type BulkCreateOrders struct {
+	Mode  string        `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Items []CreateOrder `json:"items" validate:"required,min=1,max=500"`
+}
+
+// BulkUpdateOrder changes the fields that are set on the order with ID. When
+// Version is set, the order must still be at that version.
+type BulkUpdateOrder struct {
+	ID      uint  `json:"id" validate:"required"`
+	Version *uint `json:"version"`
+	PartiallyUpdateOrder
+}
+
+type BulkUpdateOrders struct {
+	Mode  string            `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Items []BulkUpdateOrder `json:"items" validate:"required,min=1,max=500"`
+}
+
+type BulkDeleteOrder struct {
+	ID      uint  `json:"id" validate:"required"`
+	Version *uint `json:"version"`
+}
+
+type BulkDeleteOrders struct {
+	Mode  string            `json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Items []BulkDeleteOrder `json:"items" validate:"required,min=1,max=500"`
+}
+
+type TransitionOrder struct {
+	Status string `json:"status" validate:"required,oneof=draft submitted accepted in_progress delivered completed cancelled disputed"`
+	Reason string `json:"reason" validate:"max=500"`
+}
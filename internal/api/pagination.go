@@ -0,0 +1,8 @@
+package api
+
+type PaginationQuery struct {
+	Limit     int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset    int    `query:"offset" validate:"omitempty,min=0,excluded_with=Cursor"`
+	Cursor    string `query:"cursor"`
+	WithTotal bool   `query:"with_total"`
+}
@@ -0,0 +1,7 @@
+package api
+
+type SearchQuery struct {
+	Q     string `query:"q" validate:"required,max=200"`
+	Type  string `query:"type" validate:"omitempty,oneof=users orders"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 type CreateUser struct {
 	Name  string `json:"name" validate:"required"`
 	Email string `json:"email" validate:"required,email"`
@@ -9,3 +11,14 @@ type PartiallyUpdateUser struct {
 	Name  *string `json:"name,omitempty"`
 	Email *string `json:"email,omitempty" validate:"omitempty,email"`
 }
+
+type UserListQuery struct {
+	PaginationQuery
+	Name        string     `query:"name" validate:"omitempty,max=100"`
+	Email       string     `query:"email" validate:"omitempty,email"`
+	CreatedFrom *time.Time `query:"created_from"`
+	CreatedTo   *time.Time `query:"created_to"`
+	Sort        string     `query:"sort"`
+
+	IncludeDeleted bool `query:"include_deleted"`
+}
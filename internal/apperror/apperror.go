@@ -0,0 +1,94 @@
+// Package apperror defines the domain errors returned by repositories and
+// services. Each error carries a Kind, which decides the HTTP status, and a
+// stable machine-readable Code returned to clients.
+package apperror
+
+import "errors"
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindUnauthorized
+	KindForbidden
+	KindPreconditionFailed
+	KindPreconditionRequired
+)
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a domain error with the same code, so that a
+// wrapped copy still matches the error it was created from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return New(KindBadRequest, code, message)
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func PreconditionFailed(code, message string) *Error {
+	return New(KindPreconditionFailed, code, message)
+}
+
+func PreconditionRequired(code, message string) *Error {
+	return New(KindPreconditionRequired, code, message)
+}
+
+// Wrap returns a copy of domainErr that keeps cause as the underlying error.
+func Wrap(domainErr *Error, cause error) *Error {
+	wrapped := *domainErr
+	wrapped.Err = cause
+	return &wrapped
+}
+
+// As returns the domain error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+	return nil, false
+}
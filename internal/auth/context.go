@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+type Caller struct {
+	UserID uint
+	Role   entity.Role
+}
+
+func (c Caller) IsAdmin() bool {
+	return c.Role == entity.RoleAdmin
+}
+
+type callerKey struct{}
+
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
@@ -0,0 +1,150 @@
+// Package auth issues and verifies the JWT access and refresh tokens and
+// carries the authenticated caller through request contexts.
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+)
+
+var (
+	ErrMissingToken = apperror.Unauthorized("missing_token", "authorization bearer token is required")
+	ErrInvalidToken = apperror.Unauthorized("invalid_token", "token is invalid, expired or revoked")
+)
+
+type Claims struct {
+	Type TokenType   `json:"typ"`
+	Role entity.Role `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the id of the user the token was issued to.
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidToken
+	}
+	return uint(id), nil
+}
+
+type TokenPair struct {
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type TokenManager struct {
+	secret     []byte
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenManager(secret, issuer string, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		issuer:     issuer,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (m *TokenManager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// Issue signs a new access and refresh token pair for userID. The role is
+// embedded in the access token only; refreshing picks up role changes. The
+// returned id is the refresh token's jti, which the caller stores to allow
+// revocation.
+func (m *TokenManager) Issue(userID uint, role entity.Role) (TokenPair, string, error) {
+	now := time.Now()
+
+	access, accessExp, _, err := m.sign(userID, role, AccessToken, now, m.accessTTL)
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+
+	refresh, refreshExp, refreshID, err := m.sign(userID, "", RefreshToken, now, m.refreshTTL)
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+
+	return TokenPair{
+		TokenType:        "Bearer",
+		AccessToken:      access,
+		AccessExpiresAt:  accessExp,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: refreshExp,
+	}, refreshID, nil
+}
+
+func (m *TokenManager) sign(userID uint, role entity.Role, typ TokenType, now time.Time, ttl time.Duration) (string, time.Time, string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+
+	expiresAt := now.Add(ttl)
+	claims := Claims{
+		Type: typ,
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    m.issuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+	return signed, expiresAt, id, nil
+}
+
+// Parse verifies the signature, issuer, expiry and type of tokenString.
+func (m *TokenManager) Parse(tokenString string, typ TokenType) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, apperror.Wrap(ErrInvalidToken, err)
+	}
+	if claims.Type != typ {
+		return nil, apperror.Wrap(ErrInvalidToken, errors.New("unexpected token type"))
+	}
+	return claims, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+func TestTokenManagerIssueParse(t *testing.T) {
+	m := NewTokenManager("secret", "test", time.Minute, time.Hour)
+
+	pair, refreshID, err := m.Issue(42, entity.RoleAdmin)
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+
+	claims, err := m.Parse(pair.AccessToken, AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token error: %v", err)
+	}
+	if id, _ := claims.UserID(); id != 42 {
+		t.Fatalf("expected user 42, got %d", id)
+	}
+	if claims.Role != entity.RoleAdmin {
+		t.Fatalf("expected role admin, got %q", claims.Role)
+	}
+
+	claims, err = m.Parse(pair.RefreshToken, RefreshToken)
+	if err != nil {
+		t.Fatalf("parse refresh token error: %v", err)
+	}
+	if claims.ID != refreshID {
+		t.Fatalf("expected refresh id %q, got %q", refreshID, claims.ID)
+	}
+}
+
+func TestTokenManagerParseRejects(t *testing.T) {
+	m := NewTokenManager("secret", "test", time.Minute, time.Hour)
+	pair, _, err := m.Issue(1, entity.RoleUser)
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+
+	expired, _, err := NewTokenManager("secret", "test", -time.Minute, time.Hour).Issue(1, entity.RoleUser)
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		manager *TokenManager
+		token   string
+		typ     TokenType
+	}{
+		{"wrong type", m, pair.RefreshToken, AccessToken},
+		{"wrong secret", NewTokenManager("other", "test", time.Minute, time.Hour), pair.AccessToken, AccessToken},
+		{"wrong issuer", NewTokenManager("secret", "other", time.Minute, time.Hour), pair.AccessToken, AccessToken},
+		{"expired", m, expired.AccessToken, AccessToken},
+		{"malformed", m, "not-a-token", AccessToken},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.manager.Parse(tc.token, tc.typ); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "github.com/farisarmap/dot-backend-freelance/internal/apperror"
+
+var (
+	ErrUserNotFound      = apperror.NotFound("user_not_found", "user not found")
+	ErrOrderNotFound     = apperror.NotFound("order_not_found", "order not found")
+	ErrEmailTaken        = apperror.Conflict("email_already_exists", "email is already registered")
+	ErrOrderUserNotFound = apperror.Validation("order_user_not_found", "user of the order does not exist")
+	ErrOrderOwnerDeleted = apperror.Conflict("order_owner_deleted", "order owner is deleted, restore the user first")
+	ErrIllegalTransition = apperror.Conflict("illegal_status_transition", "order cannot move to the requested status")
+	ErrVersionMismatch   = apperror.PreconditionFailed("version_mismatch", "resource was modified, fetch it again and retry")
+	ErrVersionRequired   = apperror.PreconditionRequired("version_required", "version is required, fetch the resource and send its version")
+	ErrBulkDuplicateID   = apperror.Validation("duplicate_bulk_item", "the same id appears more than once in the request")
+	ErrBulkRolledBack    = apperror.Conflict("bulk_rolled_back", "not applied because another item of the request failed")
+	ErrInvalidImportRow  = apperror.Validation("invalid_import_row", "row of the import file cannot be read")
+)
+
+var (
+	ErrInvalidIdempotencyKey  = apperror.BadRequest("invalid_idempotency_key", "Idempotency-Key must be 1 to 255 printable ASCII characters")
+	ErrIdempotencyKeyNotFound = apperror.NotFound("idempotency_key_not_found", "idempotency key not found")
+	ErrIdempotencyKeyInUse    = apperror.Conflict("idempotency_key_in_use", "a request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyReused   = apperror.Validation("idempotency_key_reused", "Idempotency-Key was already used for a different request")
+)
+
+var (
+	ErrExportTooLarge     = apperror.Validation("export_too_large", "too many orders for a direct export, create an export job with POST /orders/exports instead")
+	ErrExportTooLargeXLSX = apperror.Validation("export_too_large_for_xlsx", "too many orders for an XLSX file, export CSV or NDJSON instead")
+	ErrExportJobNotFound  = apperror.NotFound("export_job_not_found", "export job not found")
+	ErrExportNotReady     = apperror.Conflict("export_not_ready", "export job has not completed")
+	ErrExportFileExpired  = apperror.NotFound("export_file_expired", "export file has expired")
+)
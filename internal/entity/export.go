@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ExportJobStatus string
+
+const (
+	ExportJobPending   ExportJobStatus = "pending"
+	ExportJobRunning   ExportJobStatus = "running"
+	ExportJobCompleted ExportJobStatus = "completed"
+	ExportJobFailed    ExportJobStatus = "failed"
+)
+
+// ExportJob is an asynchronous export of the orders matching Filter, the
+// JSON encoded list filter, requested by UserID. A completed job keeps its
+// file, FileName in the export directory, until ExpiresAt.
+type ExportJob struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	UserID      uint            `gorm:"not null" json:"user_id"`
+	Format      string          `gorm:"size:10;not null" json:"format"`
+	Filter      json.RawMessage `gorm:"type:jsonb;not null" json:"-"`
+	Status      ExportJobStatus `gorm:"size:20;not null;default:pending" json:"status"`
+	Rows        int64           `gorm:"not null;default:0" json:"rows"`
+	FileName    string          `gorm:"size:255;not null;default:''" json:"-"`
+	Error       string          `gorm:"not null;default:''" json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	CompletedAt *time.Time      `json:"completed_at"`
+	ExpiresAt   *time.Time      `json:"expires_at"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// IdempotencyKey records a request made with an Idempotency-Key header.
+// StatusCode is nil while the first request is still being handled; after
+// that the stored response, with its Location and ETag headers, is replayed
+// to retries until ExpiresAt.
+type IdempotencyKey struct {
+	UserID         uint   `gorm:"primaryKey;autoIncrement:false"`
+	IdempotencyKey string `gorm:"primaryKey;size:255"`
+	// Fingerprint is the SHA-256 of the request's method, path and body.
+	Fingerprint  string `gorm:"size:64;not null"`
+	StatusCode   *int
+	ContentType  string `gorm:"size:255;not null;default:''"`
+	Location     string `gorm:"not null;default:''"`
+	ETag         string `gorm:"column:etag;size:255;not null;default:''"`
+	ResponseBody []byte
+	CreatedAt    time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
@@ -1,13 +1,24 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Order struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	OrderName string    `gorm:"size:100" json:"order_name"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	OrderName  string         `gorm:"size:100" json:"order_name"`
+	UserID     uint           `gorm:"not null" json:"user_id"`
+	Status     OrderStatus    `gorm:"size:20;not null;default:draft" json:"status"`
+	Currency   string         `gorm:"size:3;not null" json:"currency"`
+	TaxRateBPS int64          `gorm:"not null" json:"tax_rate_bps"`
+	Version    uint           `gorm:"not null;default:1" json:"version"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
-	User User `gorm:"foreignKey:UserID" json:"user"`
+	User   User         `gorm:"foreignKey:UserID" json:"user"`
+	Items  []OrderItem  `gorm:"foreignKey:OrderID" json:"items"`
+	Totals *OrderTotals `gorm:"-" json:"totals,omitempty"`
 }
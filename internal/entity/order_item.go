@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"math"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"gorm.io/gorm"
+)
+
+// basisPoints is the denominator of Order.TaxRateBPS: 1000 bps = 10%.
+const basisPoints = 10000
+
+var (
+	ErrCurrencyRequired = apperror.Validation("currency_required", "currency is required when the order has items")
+	ErrCurrencyMismatch = apperror.Validation("currency_mismatch", "every item must use the order currency")
+	// ErrCurrencyChangeNeedsItems rejects a currency change that keeps the
+	// items, whose prices are in the old currency.
+	ErrCurrencyChangeNeedsItems = apperror.Validation("currency_change_requires_items", "changing the currency of an order with items requires sending the items again")
+	ErrAmountOverflow           = apperror.Validation("amount_overflow", "order amount is too large")
+)
+
+// OrderItem is a line of an order. UnitPrice is in the minor unit of Currency
+// (e.g. cents), so amounts are never stored as floats.
+type OrderItem struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OrderID     uint      `gorm:"not null" json:"order_id"`
+	Description string    `gorm:"size:255;not null" json:"description"`
+	Quantity    int64     `gorm:"not null" json:"quantity"`
+	UnitPrice   int64     `gorm:"not null" json:"unit_price"`
+	Currency    string    `gorm:"size:3;not null" json:"currency"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type OrderTotals struct {
+	Currency string `json:"currency"`
+	Subtotal int64  `json:"subtotal"`
+	Tax      int64  `json:"tax"`
+	Total    int64  `json:"total"`
+}
+
+// CalculateTotals sums the items of the order and applies its tax rate,
+// rounding the tax half up to the nearest minor unit.
+func (o *Order) CalculateTotals() error {
+	var subtotal int64
+	if len(o.Items) > 0 && o.Currency == "" {
+		return ErrCurrencyRequired
+	}
+
+	for _, item := range o.Items {
+		if item.Currency != o.Currency {
+			return ErrCurrencyMismatch
+		}
+		amount, ok := mulInt64(item.Quantity, item.UnitPrice)
+		if !ok {
+			return ErrAmountOverflow
+		}
+		if subtotal, ok = addInt64(subtotal, amount); !ok {
+			return ErrAmountOverflow
+		}
+	}
+
+	totals, err := TotalsFromSubtotal(o.Currency, subtotal, o.TaxRateBPS)
+	if err != nil {
+		return err
+	}
+	o.Totals = &totals
+	return nil
+}
+
+// TotalsFromSubtotal applies taxRateBPS to the sum of the items of an order,
+// rounding the tax half up like CalculateTotals.
+func TotalsFromSubtotal(currency string, subtotal, taxRateBPS int64) (OrderTotals, error) {
+	totals := OrderTotals{Currency: currency, Subtotal: subtotal}
+	taxed, ok := mulInt64(subtotal, taxRateBPS)
+	if !ok {
+		return OrderTotals{}, ErrAmountOverflow
+	}
+	totals.Tax = (taxed + basisPoints/2) / basisPoints
+	if totals.Total, ok = addInt64(subtotal, totals.Tax); !ok {
+		return OrderTotals{}, ErrAmountOverflow
+	}
+	return totals, nil
+}
+
+// AfterFind fills Totals for orders read with their items preloaded; the
+// items of orders read without them are nil. An order whose totals cannot
+// be calculated, such as one with an item in another currency, is still
+// read, without totals, rather than failing the whole query.
+func (o *Order) AfterFind(tx *gorm.DB) error {
+	if o.Items == nil {
+		return nil
+	}
+	if err := o.CalculateTotals(); err != nil {
+		o.Totals = nil
+	}
+	return nil
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if a < 0 || b < 0 || a > math.MaxInt64/b {
+		return 0, false
+	}
+	return a * b, true
+}
+
+func addInt64(a, b int64) (int64, bool) {
+	if a < 0 || b < 0 || a > math.MaxInt64-b {
+		return 0, false
+	}
+	return a + b, true
+}
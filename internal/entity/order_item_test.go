@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestOrderCalculateTotals(t *testing.T) {
+	testCases := []struct {
+		name    string
+		order   Order
+		want    OrderTotals
+		wantErr error
+	}{
+		{
+			name:  "No items",
+			order: Order{},
+			want:  OrderTotals{},
+		},
+		{
+			name: "Items with tax rounded half up",
+			order: Order{
+				Currency:   "IDR",
+				TaxRateBPS: 1100,
+				Items: []OrderItem{
+					{Quantity: 3, UnitPrice: 1999, Currency: "IDR"},
+					{Quantity: 1, UnitPrice: 5, Currency: "IDR"},
+				},
+			},
+			// subtotal 6002, tax 660.22 -> 660
+			want: OrderTotals{Currency: "IDR", Subtotal: 6002, Tax: 660, Total: 6662},
+		},
+		{
+			name: "Half minor unit rounds up",
+			order: Order{
+				Currency:   "USD",
+				TaxRateBPS: 500,
+				Items:      []OrderItem{{Quantity: 1, UnitPrice: 10, Currency: "USD"}},
+			},
+			// tax 0.5 -> 1
+			want: OrderTotals{Currency: "USD", Subtotal: 10, Tax: 1, Total: 11},
+		},
+		{
+			name: "Currency mismatch",
+			order: Order{
+				Currency: "USD",
+				Items:    []OrderItem{{Quantity: 1, UnitPrice: 10, Currency: "EUR"}},
+			},
+			wantErr: ErrCurrencyMismatch,
+		},
+		{
+			name: "Missing currency",
+			order: Order{
+				Items: []OrderItem{{Quantity: 1, UnitPrice: 10}},
+			},
+			wantErr: ErrCurrencyRequired,
+		},
+		{
+			name: "Overflow",
+			order: Order{
+				Currency: "USD",
+				Items:    []OrderItem{{Quantity: 2, UnitPrice: math.MaxInt64/2 + 1, Currency: "USD"}},
+			},
+			wantErr: ErrAmountOverflow,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.order.CalculateTotals()
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.order.Totals == nil || *tc.order.Totals != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, tc.order.Totals)
+			}
+		})
+	}
+}
+
+func TestOrderAfterFind(t *testing.T) {
+	order := Order{Currency: "USD"}
+	if err := order.AfterFind(nil); err != nil || order.Totals != nil {
+		t.Fatalf("order without loaded items: got totals %+v, err %v", order.Totals, err)
+	}
+
+	order.Items = []OrderItem{{Quantity: 2, UnitPrice: 5, Currency: "USD"}}
+	if err := order.AfterFind(nil); err != nil || order.Totals == nil || order.Totals.Total != 10 {
+		t.Fatalf("order with items: got totals %+v, err %v", order.Totals, err)
+	}
+
+	order.Items = append(order.Items, OrderItem{Quantity: 1, UnitPrice: 5, Currency: "EUR"})
+	if err := order.AfterFind(nil); err != nil || order.Totals != nil {
+		t.Fatalf("order with a mismatched item: got totals %+v, err %v", order.Totals, err)
+	}
+}
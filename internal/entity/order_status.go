@@ -0,0 +1,75 @@
+package entity
+
+import "time"
+
+type OrderStatus string
+
+const (
+	OrderStatusDraft      OrderStatus = "draft"
+	OrderStatusSubmitted  OrderStatus = "submitted"
+	OrderStatusAccepted   OrderStatus = "accepted"
+	OrderStatusInProgress OrderStatus = "in_progress"
+	OrderStatusDelivered  OrderStatus = "delivered"
+	OrderStatusCompleted  OrderStatus = "completed"
+	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusDisputed   OrderStatus = "disputed"
+)
+
+// orderTransitions lists, for every status, the statuses an order may move to
+// next. Completed and cancelled orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusDraft:      {OrderStatusSubmitted, OrderStatusCancelled},
+	OrderStatusSubmitted:  {OrderStatusAccepted, OrderStatusDraft, OrderStatusCancelled},
+	OrderStatusAccepted:   {OrderStatusInProgress, OrderStatusCancelled},
+	OrderStatusInProgress: {OrderStatusDelivered, OrderStatusDisputed, OrderStatusCancelled},
+	OrderStatusDelivered:  {OrderStatusCompleted, OrderStatusInProgress, OrderStatusDisputed},
+	OrderStatusDisputed:   {OrderStatusInProgress, OrderStatusCompleted, OrderStatusCancelled},
+	OrderStatusCompleted:  {},
+	OrderStatusCancelled:  {},
+}
+
+// providerStatuses are the statuses set by the side doing the work, which
+// admins act for. The owner of an order cannot move it to them.
+var providerStatuses = map[OrderStatus]bool{
+	OrderStatusAccepted:   true,
+	OrderStatusInProgress: true,
+	OrderStatusCompleted:  true,
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderOnly reports whether only admins may move an order to s.
+func (s OrderStatus) ProviderOnly() bool {
+	return providerStatuses[s]
+}
+
+// NextStatuses returns the statuses an order in status s may move to.
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	return append([]OrderStatus(nil), orderTransitions[s]...)
+}
+
+type OrderStatusHistory struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	OrderID    uint        `gorm:"not null" json:"order_id"`
+	FromStatus OrderStatus `gorm:"size:20;not null" json:"from_status"`
+	ToStatus   OrderStatus `gorm:"size:20;not null" json:"to_status"`
+	ChangedBy  *uint       `json:"changed_by"`
+	Reason     string      `gorm:"size:500" json:"reason"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
@@ -0,0 +1,40 @@
+package entity
+
+import "testing"
+
+func TestOrderStatusTransitions(t *testing.T) {
+	testCases := []struct {
+		from OrderStatus
+		to   OrderStatus
+		want bool
+	}{
+		{OrderStatusDraft, OrderStatusSubmitted, true},
+		{OrderStatusDraft, OrderStatusCompleted, false},
+		{OrderStatusSubmitted, OrderStatusAccepted, true},
+		{OrderStatusAccepted, OrderStatusInProgress, true},
+		{OrderStatusInProgress, OrderStatusDelivered, true},
+		{OrderStatusDelivered, OrderStatusCompleted, true},
+		{OrderStatusDelivered, OrderStatusDisputed, true},
+		{OrderStatusDisputed, OrderStatusCompleted, true},
+		{OrderStatusCompleted, OrderStatusInProgress, false},
+		{OrderStatusCancelled, OrderStatusDraft, false},
+		{OrderStatusDraft, OrderStatusDraft, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.from)+"->"+string(tc.to), func(t *testing.T) {
+			if got := tc.from.CanTransitionTo(tc.to); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderStatusValid(t *testing.T) {
+	if !OrderStatusInProgress.Valid() {
+		t.Fatalf("expected in_progress to be valid")
+	}
+	if OrderStatus("shipped").Valid() {
+		t.Fatalf("expected shipped to be invalid")
+	}
+}
@@ -0,0 +1,8 @@
+package entity
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
@@ -0,0 +1,14 @@
+package entity
+
+const (
+	SearchTypeUser  = "user"
+	SearchTypeOrder = "order"
+)
+
+type SearchHit struct {
+	Type    string  `json:"type"`
+	ID      uint    `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
@@ -1,13 +1,30 @@
 package entity
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:100;not null" json:"name"`
-	Email     string    `gorm:"unique;size:100;not null" json:"email"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	Name  string `gorm:"size:100;not null" json:"name"`
+	Email string `gorm:"uniqueIndex:idx_users_email_active,expression:LOWER(email),where:deleted_at IS NULL;size:100;not null" json:"email"`
+	// PasswordHash is the bcrypt hash of the user's password. It is empty for
+	// users created through POST /users, who cannot log in.
+	PasswordHash string         `gorm:"size:255;not null;default:''" json:"-"`
+	Role         Role           `gorm:"size:20;not null;default:user" json:"role"`
+	Version      uint           `gorm:"not null;default:1" json:"version"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	Orders []Order `gorm:"foreignKey:UserID" json:"orders"`
 }
+
+// NormalizeEmail returns email as it is stored. Emails are lower-cased, so an
+// address belongs to a single user whatever its case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(email)
+}
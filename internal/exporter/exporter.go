@@ -0,0 +1,179 @@
+// Package exporter writes rows to CSV, NDJSON and XLSX files one at a time,
+// so exports of any size are written in constant memory.
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the encoding of an export file.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+// ContentType returns the media type of files in the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Extension returns the file name extension of the format, with its dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// ErrTooManyRows is returned by Write when the format cannot hold another
+// row.
+var ErrTooManyRows = errors.New("too many rows for the export format")
+
+// Writer encodes the rows of an export file. Each row is a struct whose json
+// tags name the columns.
+type Writer[T any] interface {
+	Write(row T) error
+	// Close writes what is left of the file. It does not close the
+	// underlying writer.
+	Close() error
+}
+
+// NewWriter returns a Writer of rows of type T to w in the given format. The
+// CSV and XLSX header rows are written before the first row, so a file
+// without rows still names its columns.
+func NewWriter[T any](w io.Writer, format Format) (Writer[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rows must be structs, got %s", typ)
+	}
+	columns := structColumns(typ)
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.name
+		}
+		if err := cw.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvWriter[T]{w: cw, columns: columns, record: make([]string, len(columns))}, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter[T]{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatXLSX:
+		return newXLSXWriter[T](w, columns)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// column is an exported struct field and its json name.
+type column struct {
+	name  string
+	index int
+}
+
+func structColumns(typ reflect.Type) []column {
+	columns := make([]column, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, column{name: name, index: i})
+	}
+	return columns
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// cellText formats a field as text. Nil values are empty, times are
+// RFC 3339 in UTC and fields that are neither strings, numbers nor booleans
+// are JSON.
+func cellText(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return "", nil
+		}
+	}
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).UTC().Format(time.RFC3339), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	}
+	raw, err := json.Marshal(v.Interface())
+	return string(raw), err
+}
+
+type csvWriter[T any] struct {
+	w       *csv.Writer
+	columns []column
+	record  []string
+}
+
+func (w *csvWriter[T]) Write(row T) error {
+	v := reflect.ValueOf(row)
+	for i, column := range w.columns {
+		text, err := cellText(v.Field(column.index))
+		if err != nil {
+			return fmt.Errorf("column %s: %w", column.name, err)
+		}
+		w.record[i] = text
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter[T]) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonWriter[T any] struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter[T]) Write(row T) error {
+	return w.enc.Encode(row)
+}
+
+func (w *ndjsonWriter[T]) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID      uint       `json:"id"`
+	Name    string     `json:"name"`
+	Amount  int64      `json:"amount"`
+	Active  bool       `json:"active"`
+	At      time.Time  `json:"at"`
+	Deleted *time.Time `json:"deleted_at"`
+	Tags    []string   `json:"tags"`
+	Ignored string     `json:"-"`
+}
+
+var at = time.Date(2024, 5, 1, 10, 30, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+func write(t *testing.T, format Format, rows ...row) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter[row](&buf, format)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := string(write(t, FormatCSV,
+		row{ID: 1, Name: "Print, \"A3\"", Amount: -250, Active: true, At: at, Tags: []string{"x"}, Ignored: "no"},
+		row{ID: 2, Name: "Scan", At: at, Deleted: &at},
+	))
+	want := "id,name,amount,active,at,deleted_at,tags\n" +
+		"1,\"Print, \"\"A3\"\"\",-250,true,2024-05-01T03:30:00Z,,\"[\"\"x\"\"]\"\n" +
+		"2,Scan,0,false,2024-05-01T03:30:00Z,2024-05-01T03:30:00Z,\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCSVWriterWithoutRows(t *testing.T) {
+	if got := string(write(t, FormatCSV)); got != "id,name,amount,active,at,deleted_at,tags\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	got := string(write(t, FormatNDJSON, row{ID: 1, Name: "Print", At: at}, row{ID: 2}))
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), got)
+	}
+	if !strings.HasPrefix(lines[0], `{"id":1,"name":"Print",`) || strings.Contains(lines[0], "Ignored") {
+		t.Errorf("line 1: got %s", lines[0])
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	out := write(t, FormatXLSX,
+		row{ID: 1, Name: "<Print> & \x01", Amount: 1 << 60, Active: true, At: at},
+	)
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="G1" t="inlineStr"><is><t xml:space="preserve">tags</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`&lt;Print&gt; &amp; ` + "\uFFFD",
+		`<c r="C2" t="inlineStr"><is><t xml:space="preserve">1152921504606846976</t></is></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+		`2024-05-01T03:30:00Z`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="F2"`) {
+		t.Errorf("empty cell written:\n%s", sheet)
+	}
+}
+
+func TestColumnRef(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnRef(i); got != want {
+			t.Errorf("columnRef(%d): got %s, want %s", i, got, want)
+		}
+	}
+}
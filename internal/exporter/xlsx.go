@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// MaxXLSXRows is the number of rows a worksheet holds, the header included.
+const MaxXLSXRows = 1 << 20
+
+// maxExactNumber is the largest integer a spreadsheet stores exactly. Larger
+// integers are written as text.
+const maxExactNumber = 1 << 53
+
+// The parts of a workbook with a single worksheet, apart from the worksheet
+// itself.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter streams the rows into the worksheet of a workbook. Cells are
+// written inline, without a shared string table, so nothing but the current
+// row is kept in memory.
+type xlsxWriter[T any] struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	columns []column
+	refs    []string
+	rows    int
+}
+
+func newXLSXWriter[T any](w io.Writer, columns []column) (*xlsxWriter[T], error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxWriter[T]{
+		zip:     zw,
+		sheet:   bufio.NewWriter(f),
+		columns: columns,
+		refs:    make([]string, len(columns)),
+	}
+	for i := range columns {
+		xw.refs[i] = columnRef(i)
+	}
+
+	xw.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	xw.startRow()
+	for i, column := range columns {
+		xw.stringCell(i, column.name)
+	}
+	xw.sheet.WriteString(`</row>`)
+	return xw, nil
+}
+
+func (w *xlsxWriter[T]) Write(row T) error {
+	if w.rows >= MaxXLSXRows {
+		return ErrTooManyRows
+	}
+	v := reflect.ValueOf(row)
+	w.startRow()
+	for i, column := range w.columns {
+		if err := w.cell(i, v.Field(column.index)); err != nil {
+			return fmt.Errorf("column %s: %w", column.name, err)
+		}
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (w *xlsxWriter[T]) Close() error {
+	w.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+func (w *xlsxWriter[T]) startRow() {
+	w.rows++
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows)
+}
+
+// cell writes integers as numbers, booleans as booleans and everything else
+// as text. Empty cells are left out.
+func (w *xlsxWriter[T]) cell(i int, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); n > -maxExactNumber && n < maxExactNumber {
+			w.numberCell(i, strconv.FormatInt(n, 10))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n := v.Uint(); n < maxExactNumber {
+			w.numberCell(i, strconv.FormatUint(n, 10))
+			return nil
+		}
+	case reflect.Bool:
+		value := "0"
+		if v.Bool() {
+			value = "1"
+		}
+		fmt.Fprintf(w.sheet, `<c r="%s%d" t="b"><v>%s</v></c>`, w.refs[i], w.rows, value)
+		return nil
+	}
+
+	text, err := cellText(v)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		w.stringCell(i, text)
+	}
+	return nil
+}
+
+func (w *xlsxWriter[T]) numberCell(i int, value string) {
+	fmt.Fprintf(w.sheet, `<c r="%s%d"><v>%s</v></c>`, w.refs[i], w.rows, value)
+}
+
+// stringCell writes an inline string. Characters XML cannot hold are
+// replaced by xml.EscapeText.
+func (w *xlsxWriter[T]) stringCell(i int, text string) {
+	fmt.Fprintf(w.sheet, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, w.refs[i], w.rows)
+	xml.EscapeText(w.sheet, []byte(text))
+	w.sheet.WriteString(`</t></is></c>`)
+}
+
+// columnRef returns the letters of the column at index i: A, B, ..., Z, AA.
+func columnRef(i int) string {
+	ref := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		ref = string(rune('A'+(i-1)%26)) + ref
+	}
+	return ref
+}
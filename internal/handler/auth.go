@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type AuthHandler struct {
+	authService service.AuthService
+}
+
+func NewAuthHandler(authService service.AuthService) *AuthHandler {
+	return &AuthHandler{authService}
+}
+
+func (h *AuthHandler) Register(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.Register
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	user, err := h.authService.Register(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, pkg.ResponseSuccess("User registered", user))
+}
+
+func (h *AuthHandler) Login(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.Login
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	tokens, err := h.authService.Login(ctx, req.Email, req.Password)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Logged in", tokens))
+}
+
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.RefreshToken
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	tokens, err := h.authService.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Token refreshed", tokens))
+}
+
+func (h *AuthHandler) Logout(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.RefreshToken
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	if err := h.authService.Logout(ctx, req.RefreshToken); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Logged out", nil))
+}
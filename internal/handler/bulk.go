@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// Statuses of the items of a bulk response.
+const (
+	bulkItemOK         = "ok"
+	bulkItemFailed     = "failed"
+	bulkItemRolledBack = "rolled_back"
+)
+
+// bulkItemResult reports one item of a bulk request, at the index it had in
+// the request.
+type bulkItemResult struct {
+	Index   int         `json:"index"`
+	Status  string      `json:"status"`
+	Data    interface{} `json:"data,omitempty"`
+	Code    string      `json:"code,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+
+	httpStatus int
+}
+
+// fail records err on the result. Items left out of an atomic request
+// because another item failed are reported as rolled back.
+func (r *bulkItemResult) fail(err error) {
+	status, resp := pkg.ErrorResponse(err, http.StatusBadRequest)
+	r.Status = bulkItemFailed
+	if errors.Is(err, entity.ErrBulkRolledBack) {
+		r.Status = bulkItemRolledBack
+	}
+	r.Code = resp.Code
+	r.Error = resp.Message
+	r.Details = resp.Data
+	r.httpStatus = status
+}
+
+// bulkApply runs the items that passed validation, in request order.
+type bulkApply[T any] func(items []T, atomic bool) ([]service.BulkOutcome, error)
+
+// respondBulk validates every item of a bulk request on its own, runs the
+// valid ones through apply and writes one result per item. check, when not
+// nil, is run on each item that passed validation. In the atomic mode an
+// invalid item keeps apply from running at all. The response status is
+// successStatus when at least one item was applied and the status of the
+// first failure otherwise.
+func respondBulk[T any](c echo.Context, mode string, items []T, withData bool, successStatus int, check func(T) error, apply bulkApply[T]) error {
+	atomic := mode != api.BulkModeBestEffort
+	validate := validator.New()
+
+	results := make([]bulkItemResult, len(items))
+	valid := make([]T, 0, len(items))
+	validIndex := make([]int, 0, len(items))
+	for i, item := range items {
+		results[i] = bulkItemResult{Index: i, Status: bulkItemOK}
+		if err := validate.Struct(item); err != nil {
+			results[i].fail(err)
+			continue
+		}
+		if check != nil {
+			if err := check(item); err != nil {
+				results[i].fail(err)
+				continue
+			}
+		}
+		valid = append(valid, item)
+		validIndex = append(validIndex, i)
+	}
+
+	if atomic && len(valid) < len(items) {
+		for _, i := range validIndex {
+			results[i].fail(entity.ErrBulkRolledBack)
+		}
+	} else if len(valid) > 0 {
+		outcomes, err := apply(valid, atomic)
+		if err != nil {
+			return err
+		}
+		for j, outcome := range outcomes {
+			result := &results[validIndex[j]]
+			if outcome.Err != nil {
+				result.fail(outcome.Err)
+			} else if withData {
+				result.Data = outcome.Order
+			}
+		}
+	}
+
+	var firstFailure *bulkItemResult
+	for i := range results {
+		switch results[i].Status {
+		case bulkItemOK:
+			return c.JSON(successStatus, pkg.ResponseSuccess("Bulk request processed", results))
+		case bulkItemFailed:
+			if firstFailure == nil {
+				firstFailure = &results[i]
+			}
+		}
+	}
+
+	resp := pkg.ResponseError("Bulk request not applied", results)
+	resp.Code = firstFailure.Code
+	return c.JSON(firstFailure.httpStatus, resp)
+}
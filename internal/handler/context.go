@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestContext returns the context passed to services: the request's own
+// context, which carries the authenticated caller, the trace span, the
+// client's cancellation and the handler timeout set by middleware.Timeout.
+// Canceling it stops any work the handler started once it returns.
+func requestContext(c echo.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(c.Request().Context())
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/exporter"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type ExportHandler struct {
+	exportService service.ExportService
+}
+
+func NewExportHandler(exportService service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService}
+}
+
+// exportJobResult is an export job with the link to its file once it has
+// completed.
+type exportJobResult struct {
+	entity.ExportJob
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func newExportJobResult(job entity.ExportJob) exportJobResult {
+	result := exportJobResult{ExportJob: job}
+	if job.Status == entity.ExportJobCompleted {
+		result.DownloadURL = fmt.Sprintf("/orders/exports/%d/download", job.ID)
+	}
+	return result
+}
+
+// bindExportQuery reads the filters and format of an export from the query,
+// which POST /orders/exports takes as well.
+func bindExportQuery(c echo.Context) (api.OrderExportQuery, error) {
+	var req api.OrderExportQuery
+
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &req); err != nil {
+		return req, err
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return req, err
+	}
+
+	if req.Format == "" {
+		req.Format = string(exporter.FormatCSV)
+	}
+	return req, nil
+}
+
+// ExportOrders streams the orders matching the list filters as a file. The
+// rows are written as they are read, so an error after the first of them
+// can no longer be reported; the connection is then aborted to keep the
+// client from taking a truncated file for a complete one.
+func (h *ExportHandler) ExportOrders(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	req, err := bindExportQuery(c)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+	filter, err := toOrderFilter(req.OrderFilterQuery)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	format := exporter.Format(req.Format)
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="orders%s"`, format.Extension()))
+
+	err = h.exportService.ExportOrders(ctx, filter, format, res)
+	if err == nil {
+		if !res.Committed {
+			res.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+	if !res.Committed {
+		res.Header().Del(echo.HeaderContentType)
+		res.Header().Del(echo.HeaderContentDisposition)
+		return err
+	}
+
+	logging.FromContext(ctx).ErrorContext(ctx, "order export aborted", "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+// CreateExportJob starts an export in the background. It takes the same
+// query parameters as ExportOrders.
+func (h *ExportHandler) CreateExportJob(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	req, err := bindExportQuery(c)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+	filter, err := toOrderFilter(req.OrderFilterQuery)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	job, err := h.exportService.CreateExportJob(ctx, filter, exporter.Format(req.Format))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/orders/exports/%d", job.ID))
+	return c.JSON(http.StatusAccepted, pkg.ResponseSuccess("Export job created", newExportJobResult(job)))
+}
+
+func (h *ExportHandler) GetExportJob(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	job, err := h.exportService.GetExportJob(ctx, uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", newExportJobResult(job)))
+}
+
+// DownloadExport serves the file of a completed export job.
+func (h *ExportHandler) DownloadExport(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	job, path, err := h.exportService.ExportFile(ctx, uint(id))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, exporter.Format(job.Format).ContentType())
+	return c.Attachment(path, job.FileName)
+}
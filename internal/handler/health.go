@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/health"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	cacheBreaker *adapter.CircuitBreaker
+	checker      *health.Checker
+	cacheChecker *health.Checker
+}
+
+// NewHealthHandler reports readiness with checker and the state of the cache
+// with cacheChecker, whose checks are kept out of readiness since the API
+// keeps serving without Redis.
+func NewHealthHandler(cacheBreaker *adapter.CircuitBreaker, checker, cacheChecker *health.Checker) *HealthHandler {
+	return &HealthHandler{cacheBreaker, checker, cacheChecker}
+}
+
+// cacheHealth is the state of the circuit breaker along with the checks of
+// the cache store.
+type cacheHealth struct {
+	adapter.BreakerStatus
+	Checks map[string]health.CheckResult `json:"checks,omitempty"`
+}
+
+// Live reports that the process is up. It checks no dependency, so a
+// restart is only triggered when the server itself stops responding.
+func (h *HealthHandler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Alive", health.Report{Status: health.StatusOK}))
+}
+
+// Ready reports whether the dependencies are usable and the server is not
+// shutting down. It responds 503 otherwise.
+func (h *HealthHandler) Ready(c echo.Context) error {
+	report := h.checker.Ready(c.Request().Context())
+	if report.Status != health.StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, pkg.ResponseError("Not ready", report))
+	}
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Ready", report))
+}
+
+// Cache reports the state of the Redis circuit breaker and whether Redis
+// answers. The API keeps serving from the database while Redis is down, so
+// the status is always 200.
+func (h *HealthHandler) Cache(c echo.Context) error {
+	report := h.cacheChecker.Ready(c.Request().Context())
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", cacheHealth{h.cacheBreaker.Status(), report.Checks}))
+}
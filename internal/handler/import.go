@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/importer"
+	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type ImportHandler struct {
+	importService service.ImportService
+}
+
+func NewImportHandler(importService service.ImportService) *ImportHandler {
+	return &ImportHandler{importService}
+}
+
+// importRowError reports a row of the file that was not imported.
+type importRowError struct {
+	Line    int         `json:"line"`
+	Code    string      `json:"code,omitempty"`
+	Error   string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+type importResult struct {
+	Rows            int              `json:"rows"`
+	Imported        int              `json:"imported"`
+	Failed          int              `json:"failed"`
+	Errors          []importRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated,omitempty"`
+	StoppedAtLine   int              `json:"stopped_at_line,omitempty"`
+}
+
+func newImportResult(report service.ImportReport) importResult {
+	result := importResult{
+		Rows:            report.Rows,
+		Imported:        report.Imported,
+		Failed:          report.Failed,
+		Errors:          make([]importRowError, 0, len(report.Failures)),
+		ErrorsTruncated: report.Truncated(),
+		StoppedAtLine:   report.StoppedAt,
+	}
+	for _, failure := range report.Failures {
+		_, resp := pkg.ErrorResponse(failure.Err, http.StatusBadRequest)
+		result.Errors = append(result.Errors, importRowError{
+			Line:    failure.Line,
+			Code:    resp.Code,
+			Error:   resp.Message,
+			Details: resp.Data,
+		})
+	}
+	return result
+}
+
+// Import reads the request body, a CSV or NDJSON file, without buffering it
+// and reports the rows that were not imported. The batches written before an
+// error are kept, so the error response carries the report up to the line
+// the import stopped at.
+func (h *ImportHandler) Import(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.ImportQuery
+
+	// The body is the file, so only the query is bound.
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	format := importer.Format(req.Format)
+	if format == "" {
+		var ok bool
+		if format, ok = importer.FormatFromContentType(c.Request().Header.Get(echo.HeaderContentType)); !ok {
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be text/csv or application/x-ndjson, or set the format parameter")
+		}
+	}
+	rows, err := importer.NewReader(c.Request().Body, format)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	var report service.ImportReport
+	switch req.Type {
+	case api.ImportTypeUsers:
+		report, err = h.importService.ImportUsers(ctx, rows)
+	case api.ImportTypeOrders:
+		report, err = h.importService.ImportOrders(ctx, rows)
+	}
+	if err != nil && report.Rows > 0 {
+		return importStopped(c, err, report)
+	}
+	if errors.Is(err, importer.ErrInvalidFile) {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Import finished", newImportResult(report)))
+}
+
+// importStopped writes the error that stopped an import along with the
+// report of the rows read until then.
+func importStopped(c echo.Context, err error, report service.ImportReport) error {
+	fallback := http.StatusInternalServerError
+	if errors.Is(err, importer.ErrInvalidFile) {
+		fallback = http.StatusBadRequest
+	}
+	status, resp := pkg.ErrorResponse(err, fallback)
+	resp.Data = newImportResult(report)
+	if jsonErr := c.JSON(status, resp); jsonErr != nil {
+		return jsonErr
+	}
+	// The response is written; returning the error still has it logged.
+	return err
+}
@@ -1,13 +1,13 @@
 package handler
 
 import (
-	"context"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
 	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
 	"github.com/farisarmap/dot-backend-freelance/internal/service"
 	"github.com/farisarmap/dot-backend-freelance/pkg"
 	"github.com/go-playground/validator/v10"
@@ -17,29 +17,95 @@ import (
 type OrderHandler struct {
 	orderService service.OrderService
 	cacheManager adapter.CacheManager
+
+	// requireVersion fails the items of bulk updates and deletes that have
+	// no version, as RequireIfMatch does for single orders.
+	requireVersion bool
 }
 
-func NewOrderHandler(orderService service.OrderService, cacheManager adapter.CacheManager) *OrderHandler {
+func NewOrderHandler(orderService service.OrderService, cacheManager adapter.CacheManager, requireVersion bool) *OrderHandler {
 	return &OrderHandler{
-		orderService: orderService,
-		cacheManager: cacheManager,
+		orderService:   orderService,
+		cacheManager:   cacheManager,
+		requireVersion: requireVersion,
+	}
+}
+
+// checkVersion fails a bulk item without a version when versions are
+// required.
+func (h *OrderHandler) checkVersion(version *uint) error {
+	if h.requireVersion && version == nil {
+		return entity.ErrVersionRequired
 	}
+	return nil
 }
 
 func (h *OrderHandler) GetAllOrders(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	return h.listOrders(c, false)
+}
+
+// GetMyOrders lists the orders of the caller. It accepts the same query
+// parameters as GetAllOrders except user_id.
+func (h *OrderHandler) GetMyOrders(c echo.Context) error {
+	return h.listOrders(c, true)
+}
+
+func (h *OrderHandler) listOrders(c echo.Context, mine bool) error {
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
-	orders, err := h.orderService.GetAllOrders(ctx)
+	var req api.OrderListQuery
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	filter, err := toOrderFilter(req.OrderFilterQuery)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+	if mine {
+		caller, ok := auth.CallerFromContext(ctx)
+		if !ok {
+			return auth.ErrMissingToken
+		}
+		filter.UserID = &caller.UserID
+	}
+
+	orders, pageInfo, err := h.orderService.GetAllOrders(ctx, filter, toPageQuery(req.PaginationQuery))
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
-	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", orders))
+	resp := pkg.ResponseSuccessWithPagination("Success", orders, toPagination(pageInfo))
+	return pkg.JSONWithETag(c, http.StatusOK, pkg.ListTag(resp), resp)
+}
+
+func toOrderFilter(q api.OrderFilterQuery) (adapter.OrderFilter, error) {
+	sort, err := adapter.ParseSort(q.Sort, adapter.OrderSortFields)
+	if err != nil {
+		return adapter.OrderFilter{}, err
+	}
+
+	return adapter.OrderFilter{
+		OrderName:   q.OrderName,
+		UserID:      q.UserID,
+		Status:      entity.OrderStatus(q.Status),
+		CreatedFrom: q.CreatedFrom,
+		CreatedTo:   q.CreatedTo,
+		Sort:        sort,
+
+		IncludeDeleted: q.IncludeDeleted,
+	}, nil
 }
 
 func (h *OrderHandler) CreateOrder(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	var req api.CreateOrder
@@ -52,16 +118,16 @@ func (h *OrderHandler) CreateOrder(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	order, err := h.orderService.CreateOrder(ctx, req.OrderName, req.UserID)
+	order, err := h.orderService.CreateOrder(ctx, req)
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, pkg.ResponseSuccess("Order created", order))
 }
 
 func (h *OrderHandler) GetOrderByID(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -72,14 +138,14 @@ func (h *OrderHandler) GetOrderByID(c echo.Context) error {
 
 	order, oErr := h.orderService.GetOrderByID(ctx, uint(id))
 	if oErr != nil {
-		return pkg.HandleError(c, oErr, http.StatusInternalServerError)
+		return oErr
 	}
 
-	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", order))
+	return pkg.JSONWithETag(c, http.StatusOK, pkg.EntityTag(order.Version, order), pkg.ResponseSuccess("Success", order))
 }
 
 func (h *OrderHandler) UpdateOrder(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -99,16 +165,18 @@ func (h *OrderHandler) UpdateOrder(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	order, uErr := h.orderService.UpdateOrder(ctx, uint(id), req.OrderName, req.UserID)
+	ifMatch := pkg.IfMatchVersions(c.Request().Header.Get(pkg.HeaderIfMatch))
+	order, uErr := h.orderService.UpdateOrder(ctx, uint(id), ifMatch, req)
 	if uErr != nil {
-		return pkg.HandleError(c, uErr, http.StatusInternalServerError)
+		return uErr
 	}
 
+	c.Response().Header().Set(pkg.HeaderETag, pkg.EntityTag(order.Version, order))
 	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Order updated", order))
 }
 
 func (h *OrderHandler) PartialUpdateOrder(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -128,16 +196,18 @@ func (h *OrderHandler) PartialUpdateOrder(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	order, pErr := h.orderService.PartialUpdateOrder(ctx, uint(id), req.OrderName, req.UserID)
+	ifMatch := pkg.IfMatchVersions(c.Request().Header.Get(pkg.HeaderIfMatch))
+	order, pErr := h.orderService.PartialUpdateOrder(ctx, uint(id), ifMatch, req)
 	if pErr != nil {
-		return pkg.HandleError(c, pErr, http.StatusInternalServerError)
+		return pErr
 	}
 
+	c.Response().Header().Set(pkg.HeaderETag, pkg.EntityTag(order.Version, order))
 	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Order partially updated", order))
 }
 
 func (h *OrderHandler) DeleteOrder(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -146,15 +216,148 @@ func (h *OrderHandler) DeleteOrder(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	if dErr := h.orderService.DeleteOrder(ctx, uint(id)); dErr != nil {
-		return pkg.HandleError(c, dErr, http.StatusInternalServerError)
+	ifMatch := pkg.IfMatchVersions(c.Request().Header.Get(pkg.HeaderIfMatch))
+	if dErr := h.orderService.DeleteOrder(ctx, uint(id), ifMatch); dErr != nil {
+		return dErr
 	}
 
 	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Order deleted", nil))
 }
 
+func (h *OrderHandler) BulkCreateOrders(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.BulkCreateOrders
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	return respondBulk(c, req.Mode, req.Items, true, http.StatusCreated, nil, func(items []api.CreateOrder, atomic bool) ([]service.BulkOutcome, error) {
+		return h.orderService.BulkCreateOrders(ctx, items, atomic)
+	})
+}
+
+func (h *OrderHandler) BulkUpdateOrders(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.BulkUpdateOrders
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	check := func(item api.BulkUpdateOrder) error { return h.checkVersion(item.Version) }
+	return respondBulk(c, req.Mode, req.Items, true, http.StatusOK, check, func(items []api.BulkUpdateOrder, atomic bool) ([]service.BulkOutcome, error) {
+		return h.orderService.BulkUpdateOrders(ctx, items, atomic)
+	})
+}
+
+func (h *OrderHandler) BulkDeleteOrders(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.BulkDeleteOrders
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	check := func(item api.BulkDeleteOrder) error { return h.checkVersion(item.Version) }
+	return respondBulk(c, req.Mode, req.Items, false, http.StatusOK, check, func(items []api.BulkDeleteOrder, atomic bool) ([]service.BulkOutcome, error) {
+		return h.orderService.BulkDeleteOrders(ctx, items, atomic)
+	})
+}
+
+func (h *OrderHandler) RestoreOrder(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	order, rErr := h.orderService.RestoreOrder(ctx, uint(id))
+	if rErr != nil {
+		return rErr
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Order restored", order))
+}
+
+func (h *OrderHandler) TransitionOrder(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	var req api.TransitionOrder
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	var changedBy *uint
+	if caller, ok := auth.CallerFromContext(ctx); ok {
+		changedBy = &caller.UserID
+	}
+
+	order, tErr := h.orderService.TransitionOrder(ctx, uint(id), entity.OrderStatus(req.Status), changedBy, req.Reason)
+	if tErr != nil {
+		return tErr
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Order status updated", order))
+}
+
+func (h *OrderHandler) GetOrderStatusHistory(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	history, hErr := h.orderService.GetOrderStatusHistory(ctx, uint(id))
+	if hErr != nil {
+		return hErr
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", history))
+}
+
 func (h *OrderHandler) CreateUserAndOrder(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	var req api.CreateUserAndOrderRequest
@@ -169,7 +372,7 @@ func (h *OrderHandler) CreateUserAndOrder(c echo.Context) error {
 	}
 
 	if err := h.orderService.CreateUserAndOrder(ctx, req); err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, pkg.ResponseSuccess("User and Order created successfully", nil))
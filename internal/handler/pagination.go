@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+)
+
+func toPageQuery(q api.PaginationQuery) adapter.PageQuery {
+	return adapter.PageQuery{
+		Limit:     q.Limit,
+		Offset:    q.Offset,
+		Cursor:    q.Cursor,
+		WithTotal: q.WithTotal,
+	}
+}
+
+func toPagination(info adapter.PageInfo) pkg.Pagination {
+	return pkg.Pagination{
+		Limit:      info.Limit,
+		Offset:     info.Offset,
+		NextCursor: info.NextCursor,
+		Total:      info.Total,
+	}
+}
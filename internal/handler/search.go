@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/service"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type SearchHandler struct {
+	searchService service.SearchService
+}
+
+func NewSearchHandler(searchService service.SearchService) *SearchHandler {
+	return &SearchHandler{searchService}
+}
+
+func (h *SearchHandler) Search(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var req api.SearchQuery
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	hits, err := h.searchService.Search(ctx, req.Q, req.Type, req.Limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", hits))
+}
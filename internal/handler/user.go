@@ -1,11 +1,10 @@
 package handler
 
 import (
-	"context"
 	"net/http"
 	"strconv"
-	"time"
 
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
 	"github.com/farisarmap/dot-backend-freelance/internal/api"
 	"github.com/farisarmap/dot-backend-freelance/internal/service"
 	"github.com/farisarmap/dot-backend-freelance/pkg"
@@ -22,19 +21,46 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 }
 
 func (h *UserHandler) GetAllUsers(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
-	users, err := h.userService.GetAllUsers(ctx)
+	var req api.UserListQuery
+
+	if err := c.Bind(&req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	sort, err := adapter.ParseSort(req.Sort, adapter.UserSortFields)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	filter := adapter.UserFilter{
+		Name:        req.Name,
+		Email:       req.Email,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		Sort:        sort,
+
+		IncludeDeleted: req.IncludeDeleted,
+	}
+
+	users, pageInfo, err := h.userService.GetAllUsers(ctx, filter, toPageQuery(req.PaginationQuery))
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
-	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", users))
+	resp := pkg.ResponseSuccessWithPagination("Success", users, toPagination(pageInfo))
+	return pkg.JSONWithETag(c, http.StatusOK, pkg.ListTag(resp), resp)
 }
 
 func (h *UserHandler) CreateUser(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	var req api.CreateUser
@@ -50,14 +76,14 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 
 	user, err := h.userService.CreateUser(ctx, req.Name, req.Email)
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, pkg.ResponseSuccess("User created", user))
 }
 
 func (h *UserHandler) GetUserByID(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -68,14 +94,14 @@ func (h *UserHandler) GetUserByID(c echo.Context) error {
 
 	user, err := h.userService.GetUserByID(ctx, uint(id))
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
-	return c.JSON(http.StatusOK, pkg.ResponseSuccess("Success", user))
+	return pkg.JSONWithETag(c, http.StatusOK, pkg.EntityTag(user.Version, user), pkg.ResponseSuccess("Success", user))
 }
 
 func (h *UserHandler) UpdateUser(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -95,16 +121,18 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	user, err := h.userService.UpdateUser(ctx, uint(id), req.Name, req.Email)
+	ifMatch := pkg.IfMatchVersions(c.Request().Header.Get(pkg.HeaderIfMatch))
+	user, err := h.userService.UpdateUser(ctx, uint(id), ifMatch, req.Name, req.Email)
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
+	c.Response().Header().Set(pkg.HeaderETag, pkg.EntityTag(user.Version, user))
 	return c.JSON(http.StatusOK, pkg.ResponseSuccess("User updated", user))
 }
 
 func (h *UserHandler) PartialUpdateUser(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -123,16 +151,18 @@ func (h *UserHandler) PartialUpdateUser(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	user, err := h.userService.PartialUpdateUser(ctx, uint(id), req.Name, req.Email)
+	ifMatch := pkg.IfMatchVersions(c.Request().Header.Get(pkg.HeaderIfMatch))
+	user, err := h.userService.PartialUpdateUser(ctx, uint(id), ifMatch, req.Name, req.Email)
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
+	c.Response().Header().Set(pkg.HeaderETag, pkg.EntityTag(user.Version, user))
 	return c.JSON(http.StatusOK, pkg.ResponseSuccess("User partially updated", user))
 }
 
 func (h *UserHandler) DeleteUser(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := requestContext(c)
 	defer cancel()
 
 	idParam := c.Param("id")
@@ -141,10 +171,29 @@ func (h *UserHandler) DeleteUser(c echo.Context) error {
 		return pkg.HandleError(c, err, http.StatusBadRequest)
 	}
 
-	err = h.userService.DeleteUser(ctx, uint(id))
+	ifMatch := pkg.IfMatchVersions(c.Request().Header.Get(pkg.HeaderIfMatch))
+	err = h.userService.DeleteUser(ctx, uint(id), ifMatch)
 	if err != nil {
-		return pkg.HandleError(c, err, http.StatusInternalServerError)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, pkg.ResponseSuccess("User deleted", nil))
 }
+
+func (h *UserHandler) RestoreUser(c echo.Context) error {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return pkg.HandleError(c, err, http.StatusBadRequest)
+	}
+
+	user, err := h.userService.RestoreUser(ctx, uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.ResponseSuccess("User restored", user))
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/redis/go-redis/v9"
+)
+
+// Postgres pings the database.
+func Postgres(db *sql.DB) Check {
+	return db.PingContext
+}
+
+// Redis sends PING to the Redis server.
+func Redis(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// Migrations checks that the database schema is at the latest migration
+// found in dir and is not dirty.
+func Migrations(db *sql.DB, dir string) (Check, error) {
+	expected, err := latestMigration(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		// The driver does not own db, so closing it only releases conn.
+		defer driver.Close()
+
+		version, dirty, err := driver.Version()
+		if err != nil {
+			return err
+		}
+		switch {
+		case version == database.NilVersion:
+			return fmt.Errorf("no migration applied, expected version %d", expected)
+		case dirty:
+			return fmt.Errorf("migration version %d is dirty", version)
+		case uint(version) != expected:
+			return fmt.Errorf("migration version %d, expected %d", version, expected)
+		}
+		return nil
+	}, nil
+}
+
+// latestMigration returns the highest migration version in dir.
+func latestMigration(dir string) (uint, error) {
+	src, err := source.Open("file://" + dir)
+	if err != nil {
+		return 0, fmt.Errorf("open migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("read migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read migrations: %w", err)
+		}
+		version = next
+	}
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	ctx := context.Background()
+	c := NewChecker(50 * time.Millisecond)
+	c.Add("ok", func(context.Context) error { return nil })
+
+	if report := c.Ready(ctx); report.Status != StatusOK || report.Checks["ok"].Status != StatusOK {
+		t.Fatalf("expected a passing report, got %+v", report)
+	}
+
+	c.Add("down", func(context.Context) error { return errors.New("connection refused") })
+	c.Add("hung", func(context.Context) error { select {} })
+
+	report := c.Ready(ctx)
+	if report.Status != StatusFailing {
+		t.Fatalf("expected a failing report, got %+v", report)
+	}
+	if got := report.Checks["down"]; got.Status != StatusFailing || got.Error != "connection refused" {
+		t.Fatalf("unexpected result %+v", got)
+	}
+	if got := report.Checks["hung"]; got.Status != StatusFailing || got.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected the hung check to time out, got %+v", got)
+	}
+}
+
+func TestReadyWhileDraining(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("ok", func(context.Context) error { return nil })
+	c.Drain()
+
+	if report := c.Ready(context.Background()); report.Status != StatusDraining {
+		t.Fatalf("expected a draining report, got %+v", report)
+	}
+}
+
+func TestLatestMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"001_init.up.sql", "001_init.down.sql", "002_add.up.sql", "010_more.up.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	version, err := latestMigration(dir)
+	if err != nil {
+		t.Fatalf("latestMigration: %v", err)
+	}
+	if version != 10 {
+		t.Fatalf("expected version 10, got %d", version)
+	}
+
+	if _, err := latestMigration(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,311 @@
+// Package importer streams the rows of CSV and NDJSON import files into
+// structs one at a time, so files of any size are read in constant memory.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Format is the encoding of an import file.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// MaxLineBytes bounds an NDJSON line. Longer lines are reported as row
+// errors and skipped.
+const MaxLineBytes = 1 << 20
+
+// FormatFromContentType returns the format of a request body with the given
+// Content-Type.
+func FormatFromContentType(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+// FormatFromPath returns the format of a file from its extension.
+func FormatFromPath(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+// ErrInvalidFile is wrapped by the errors of a file that cannot be read at
+// all, such as a CSV header with unknown columns.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// RowError is a row that could not be decoded. Reading can continue with the
+// next row.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader decodes the rows of an import file.
+type Reader interface {
+	// Read decodes the next row into dst, a pointer to a struct whose json
+	// tags name the columns, and returns the line the row starts on. It
+	// returns io.EOF after the last row and a *RowError for a row that
+	// cannot be decoded; any other error ends the file.
+	Read(dst interface{}) (int, error)
+}
+
+// NewReader returns a Reader for r in the given format.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		return &csvReader{r: cr}, nil
+	case FormatNDJSON:
+		return &ndjsonReader{r: bufio.NewReader(r)}, nil
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+type csvReader struct {
+	r *csv.Reader
+
+	header []string
+	// fields maps each column to the index of its struct field.
+	fields []int
+	typ    reflect.Type
+}
+
+func (r *csvReader) Read(dst interface{}) (int, error) {
+	v, err := structValue(dst)
+	if err != nil {
+		return 0, err
+	}
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+	if r.typ == nil {
+		if r.fields, err = columnFields(v.Type(), r.header); err != nil {
+			return 0, err
+		}
+		r.typ = v.Type()
+	} else if r.typ != v.Type() {
+		return 0, fmt.Errorf("rows must all be decoded into %s, got %s", r.typ, v.Type())
+	}
+
+	record, err := r.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return 0, err
+	}
+	line, _ := r.r.FieldPos(0)
+	if len(record) != len(r.header) {
+		return line, &RowError{Line: line, Err: fmt.Errorf("row has %d fields, the header has %d", len(record), len(r.header))}
+	}
+
+	v.Set(reflect.Zero(v.Type()))
+	for i, cell := range record {
+		if err := setCell(v.Field(r.fields[i]), strings.TrimSpace(cell)); err != nil {
+			return line, &RowError{Line: line, Err: fmt.Errorf("column %s: %w", r.header[i], err)}
+		}
+	}
+	return line, nil
+}
+
+func (r *csvReader) readHeader() error {
+	header, err := r.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return fmt.Errorf("%w: header: %v", ErrInvalidFile, parseErr.Err)
+		}
+		return err
+	}
+	r.header = make([]string, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		r.header[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return nil
+}
+
+// columnFields maps the columns of header to the fields of typ by their json
+// names. Unknown and repeated columns are rejected so that a misspelled
+// column is not silently ignored.
+func columnFields(typ reflect.Type, header []string) ([]int, error) {
+	byName := make(map[string]int, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		if name := jsonName(typ.Field(i)); name != "" {
+			byName[name] = i
+		}
+	}
+
+	fields := make([]int, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		field, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFile, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: column %q appears more than once", ErrInvalidFile, name)
+		}
+		seen[name] = true
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return strings.ToLower(name)
+}
+
+// setCell stores a CSV cell in field. Empty cells leave the zero value;
+// fields that are neither strings, numbers nor booleans hold JSON.
+func setCell(field reflect.Value, cell string) error {
+	if cell == "" {
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(cell)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", cell)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cell, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a positive integer", cell)
+		}
+		field.SetUint(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", cell)
+		}
+		field.SetBool(b)
+	default:
+		if err := json.Unmarshal([]byte(cell), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+type ndjsonReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (r *ndjsonReader) Read(dst interface{}) (int, error) {
+	v, err := structValue(dst)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		raw, tooLong, err := r.readLine()
+		if err != nil {
+			return 0, err
+		}
+		r.line++
+		if tooLong {
+			return r.line, &RowError{Line: r.line, Err: fmt.Errorf("line is longer than %d bytes", MaxLineBytes)}
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+
+		v.Set(reflect.Zero(v.Type()))
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(dst); err != nil {
+			return r.line, &RowError{Line: r.line, Err: err}
+		}
+		if dec.More() {
+			return r.line, &RowError{Line: r.line, Err: errors.New("line holds more than one JSON value")}
+		}
+		return r.line, nil
+	}
+}
+
+// readLine returns the next line without its line ending. A line longer
+// than MaxLineBytes is skipped and reported as tooLong.
+func (r *ndjsonReader) readLine() (line []byte, tooLong bool, err error) {
+	for {
+		chunk, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return nil, false, err
+		}
+		if !tooLong {
+			if len(line)+len(chunk) > MaxLineBytes {
+				line, tooLong = nil, true
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if !isPrefix {
+			return line, tooLong, nil
+		}
+	}
+}
+
+func structValue(dst interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("rows must be decoded into a pointer to a struct, got %T", dst)
+	}
+	return v.Elem(), nil
+}
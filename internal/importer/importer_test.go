@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Description string `json:"description"`
+	Quantity    int64  `json:"quantity"`
+}
+
+type row struct {
+	Email   string `json:"email"`
+	Count   int64  `json:"count"`
+	Limit   uint   `json:"limit"`
+	Active  bool   `json:"active"`
+	Items   []item `json:"items"`
+	Ignored string `json:"-"`
+}
+
+type result struct {
+	line int
+	row  row
+	err  error
+}
+
+func readAll(t *testing.T, r Reader) []result {
+	t.Helper()
+	var results []result
+	for {
+		var dst row
+		line, err := r.Read(&dst)
+		if errors.Is(err, io.EOF) {
+			return results
+		}
+		var rowErr *RowError
+		if err != nil && !errors.As(err, &rowErr) {
+			t.Fatalf("Read: %v", err)
+		}
+		results = append(results, result{line, dst, err})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "\ufeffEmail, count ,limit,active,items\n" +
+		"a@example.com,3,7,true,\"[{\"\"description\"\":\"\"Print\"\",\"\"quantity\"\":2}]\"\n" +
+		"b@example.com,x,1,false,\n" +
+		"c@example.com,1\n" +
+		"\"d@example.com\n\",2,3,true,\n" +
+		" e@example.com ,,,,\n"
+	r, err := NewReader(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+
+	results := readAll(t, r)
+	if len(results) != 5 {
+		t.Fatalf("got %d rows, want 5: %+v", len(results), results)
+	}
+
+	first := results[0]
+	if first.err != nil || first.line != 2 {
+		t.Fatalf("row 1: line %d, err %v", first.line, first.err)
+	}
+	if first.row.Email != "a@example.com" || first.row.Count != 3 || first.row.Limit != 7 || !first.row.Active {
+		t.Errorf("row 1: got %+v", first.row)
+	}
+	if len(first.row.Items) != 1 || first.row.Items[0].Quantity != 2 {
+		t.Errorf("row 1 items: got %+v", first.row.Items)
+	}
+
+	for i, want := range []struct {
+		line int
+		err  string
+	}{
+		{3, "column count"},
+		{4, "2 fields"},
+	} {
+		got := results[i+1]
+		if got.line != want.line || got.err == nil || !strings.Contains(got.err.Error(), want.err) {
+			t.Errorf("row %d: line %d, err %v, want line %d and %q", i+2, got.line, got.err, want.line, want.err)
+		}
+	}
+
+	if results[3].err != nil || results[3].line != 5 || results[3].row.Email != "d@example.com" {
+		t.Errorf("multi-line row: line %d, err %v, email %q", results[3].line, results[3].err, results[3].row.Email)
+	}
+	if last := results[4]; last.err != nil || last.line != 7 || last.row.Email != "e@example.com" || last.row.Count != 0 {
+		t.Errorf("last row: line %d, err %v, row %+v", last.line, last.err, last.row)
+	}
+}
+
+func TestCSVReaderRejectsUnknownColumns(t *testing.T) {
+	for _, header := range []string{"email,mail", "email,email", "email,ignored"} {
+		r, _ := NewReader(strings.NewReader(header+"\na,b\n"), FormatCSV)
+		var dst row
+		if _, err := r.Read(&dst); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("header %q: got %v, want a file error", header, err)
+		}
+	}
+}
+
+func TestCSVReaderEmptyFile(t *testing.T) {
+	r, _ := NewReader(strings.NewReader(""), FormatCSV)
+	var dst row
+	if _, err := r.Read(&dst); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want EOF", err)
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"email":"a@example.com","count":3,"items":[{"description":"Print","quantity":2}]}` + "\r\n" +
+		"\n" +
+		`{"email":"b@example.com","unknown":1}` + "\n" +
+		`{"email":` + "\n" +
+		`{"email":"c@example.com"} {"email":"d@example.com"}` + "\n" +
+		`{"email":"` + strings.Repeat("x", MaxLineBytes) + `"}` + "\n" +
+		`{"email":"e@example.com"}`
+	r, err := NewReader(strings.NewReader(input), FormatNDJSON)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+
+	results := readAll(t, r)
+	if len(results) != 6 {
+		t.Fatalf("got %d rows, want 6", len(results))
+	}
+
+	first := results[0]
+	if first.err != nil || first.line != 1 || first.row.Email != "a@example.com" || len(first.row.Items) != 1 {
+		t.Errorf("row 1: line %d, err %v, row %+v", first.line, first.err, first.row)
+	}
+	for i, want := range []struct {
+		line int
+		err  string
+	}{
+		{3, "unknown field"},
+		{4, "unexpected EOF"},
+		{5, "more than one"},
+		{6, "longer than"},
+	} {
+		got := results[i+1]
+		if got.line != want.line || got.err == nil || !strings.Contains(got.err.Error(), want.err) {
+			t.Errorf("row %d: line %d, err %v, want line %d and %q", i+2, got.line, got.err, want.line, want.err)
+		}
+	}
+	if last := results[5]; last.err != nil || last.line != 7 || last.row.Email != "e@example.com" {
+		t.Errorf("last row: line %d, err %v, row %+v", last.line, last.err, last.row)
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	testCases := map[string]Format{
+		"text/csv; charset=utf-8": FormatCSV,
+		"application/x-ndjson":    FormatNDJSON,
+		"application/jsonl":       FormatNDJSON,
+		"application/json":        "",
+	}
+	for contentType, want := range testCases {
+		if got, _ := FormatFromContentType(contentType); got != want {
+			t.Errorf("%s: got %q, want %q", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM's logs to the logger of the query's context, so that
+// they carry the request ID. Queries slower than the slow threshold are
+// logged as warnings and failed queries as errors; a lookup finding no row
+// is not a failure.
+type GormLogger struct {
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+// NewGormLogger returns a GORM logger. A zero slowThreshold disables slow
+// query logging.
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{slowThreshold: slowThreshold, level: logger.Warn}
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// ParamsFilter keeps the bound values out of the logged SQL, as they hold
+// emails, names and password hashes.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	log := FromContext(ctx)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Float64("elapsed_ms", float64(elapsed.Microseconds())/1000),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		log.ErrorContext(ctx, "query failed", append(attrs(), slog.Any("error", err))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		log.WarnContext(ctx, "slow query", append(attrs(), slog.Duration("threshold", l.slowThreshold))...)
+	case l.level >= logger.Info:
+		log.DebugContext(ctx, "query", attrs()...)
+	}
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "text")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	if _, err := New(&buf, "loud", "json"); err == nil {
+		t.Fatal("expected an unknown level to be rejected")
+	}
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+}
+
+func TestGormLoggerUsesContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil)).With("request_id", "req-1")
+	ctx := WithLogger(context.Background(), logger)
+	l := NewGormLogger(100 * time.Millisecond)
+	query := func() (string, int64) { return `SELECT * FROM "orders"`, 1 }
+
+	l.Trace(ctx, time.Now(), query, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("expected a fast query not to be logged, got %q", buf.String())
+	}
+
+	l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	if out := buf.String(); !strings.Contains(out, `"msg":"slow query"`) || !strings.Contains(out, `"request_id":"req-1"`) {
+		t.Fatalf("expected a slow query warning with the request ID, got %q", out)
+	}
+
+	buf.Reset()
+	l.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	if buf.Len() != 0 {
+		t.Fatalf("expected a missing row not to be logged, got %q", buf.String())
+	}
+	l.Trace(ctx, time.Now(), query, errors.New("deadlock detected"))
+	if out := buf.String(); !strings.Contains(out, `"msg":"query failed"`) {
+		t.Fatalf("expected a failed query error, got %q", out)
+	}
+}
+
+func TestGormLoggerOmitsBoundValues(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               NewGormLogger(0).LogMode(logger.Info),
+	})
+	if err != nil {
+		t.Fatalf("open dry run db: %v", err)
+	}
+
+	var rows []map[string]interface{}
+	db.WithContext(ctx).Table("users").Where("email = ?", "alice@example.com").Find(&rows)
+	if out := buf.String(); !strings.Contains(out, `"msg":"query"`) || strings.Contains(out, "alice@example.com") {
+		t.Fatalf("expected the query without its values, got %q", out)
+	}
+}
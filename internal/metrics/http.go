@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so that scanners
+// probing random paths cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+// Middleware records the count and latency of every request, labelled with
+// the route pattern (e.g. /orders/:id) rather than the path.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so that its
+				// status is the one recorded.
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(c.Response().Status)
+			method := c.Request().Method
+
+			m.httpRequests.WithLabelValues(method, route, status).Inc()
+			m.httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestMiddlewareLabelsByRoute(t *testing.T) {
+	m := New()
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/orders/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/orders/1", "/orders/2", "/orders/0", "/nowhere"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/orders/:id", "200")); got != 2 {
+		t.Fatalf("expected 2 successful requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/orders/:id", "404")); got != 1 {
+		t.Fatalf("expected the handler error to be recorded as 404, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")); got != 1 {
+		t.Fatalf("expected the unknown path to be recorded as unmatched, got %v", got)
+	}
+}
+
+func TestGormPluginRecordsQueries(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	m := New()
+	if err := db.Use(m.GormPlugin()); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+
+	var orders []entity.Order
+	db.Find(&orders)
+	db.Find(&orders)
+
+	if got := testutil.CollectAndCount(m.dbDuration, "db_query_duration_seconds"); got != 1 {
+		t.Fatalf("expected one series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.dbErrors.WithLabelValues("query", "orders")); got != 0 {
+		t.Fatalf("expected no errors, got %v", got)
+	}
+}
+
+func TestObserveCache(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	cache := adapter.ObserveCache(adapter.NewMemoryCache(adapter.MemoryCacheOptions{}, adapter.JSONCodec), m)
+
+	var v string
+	_ = cache.Get(ctx, "k", &v)
+	_ = cache.Set(ctx, "k", "v", 0)
+	_ = cache.Get(ctx, "k", &v)
+	_ = cache.Delete(ctx, "k")
+
+	for _, c := range []struct{ operation, result string }{
+		{"get", adapter.CacheMiss},
+		{"get", adapter.CacheHit},
+		{"set", adapter.CacheOK},
+		{"delete", adapter.CacheOK},
+	} {
+		if got := testutil.ToFloat64(m.cacheOps.WithLabelValues(c.operation, c.result)); got != 1 {
+			t.Fatalf("expected one %s %s, got %v", c.operation, c.result, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// JWTAuth rejects requests without a valid access token and stores the
+// caller in the request context.
+func JWTAuth(tokens *auth.TokenManager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				return auth.ErrMissingToken
+			}
+
+			claims, err := tokens.Parse(token, auth.AccessToken)
+			if err != nil {
+				return err
+			}
+			userID, err := claims.UserID()
+			if err != nil {
+				return err
+			}
+
+			ctx := auth.WithCaller(c.Request().Context(), auth.Caller{UserID: userID, Role: claims.Role})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireIfMatch rejects PUT, PATCH and DELETE requests without an If-Match
+// header with 428 Precondition Required, so that clients cannot overwrite
+// changes they have not seen.
+func RequireIfMatch() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				if c.Request().Header.Get(pkg.HeaderIfMatch) == "" {
+					return echo.NewHTTPError(http.StatusPreconditionRequired, "If-Match header is required")
+				}
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequireIfMatch(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = pkg.HTTPErrorHandler
+	e.Use(RequireIfMatch())
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.GET("/orders/:id", ok)
+	e.PUT("/orders/:id", ok)
+	e.DELETE("/orders/:id", ok)
+
+	testCases := []struct {
+		method  string
+		ifMatch string
+		want    int
+	}{
+		{http.MethodGet, "", http.StatusNoContent},
+		{http.MethodPut, "", http.StatusPreconditionRequired},
+		{http.MethodPut, `"1-abc"`, http.StatusNoContent},
+		{http.MethodDelete, "", http.StatusPreconditionRequired},
+		{http.MethodDelete, "*", http.StatusNoContent},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, "/orders/1", nil)
+		if tc.ifMatch != "" {
+			req.Header.Set(pkg.HeaderIfMatch, tc.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s with If-Match %q: got %d, want %d", tc.method, tc.ifMatch, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyOptions configures Idempotency. Keys are remembered for TTL. A
+// request that has held its key for LockTimeout without completing is
+// assumed lost, and a retry may take the key over.
+type IdempotencyOptions struct {
+	TTL         time.Duration
+	LockTimeout time.Duration
+}
+
+// Idempotency makes retries of requests carrying an Idempotency-Key header
+// safe. The first request with a key runs and its response is stored; later
+// requests from the same caller with the same key get that response again,
+// marked with Idempotent-Replayed, without running the handler. Of the
+// response headers, only Content-Type, Location and ETag are replayed. A
+// retry that arrives while the first request is still running gets 409, and
+// reusing a key for a different method, path or body gets 422. Responses
+// with a 5xx status are not stored, so the request can be retried. Requests
+// without the header are handled as usual.
+func Idempotency(store adapter.IdempotencyRepository, opts IdempotencyOptions) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength || !printableASCII(key) {
+				return entity.ErrInvalidIdempotencyKey
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := req.Context()
+			caller, _ := auth.CallerFromContext(ctx)
+			now := time.Now()
+			record := &entity.IdempotencyKey{
+				UserID:         caller.UserID,
+				IdempotencyKey: key,
+				Fingerprint:    fingerprint(req, body),
+				CreatedAt:      now,
+				ExpiresAt:      now.Add(opts.TTL),
+			}
+
+			acquired, err := store.Acquire(ctx, record, now.Add(-opts.LockTimeout))
+			if err != nil {
+				return err
+			}
+			if !acquired {
+				return replay(c, store, record)
+			}
+
+			// Storing the outcome must not depend on the request context,
+			// which is canceled once the handler times out.
+			storeCtx := context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.Release(storeCtx, record); err != nil {
+						logging.FromContext(ctx).ErrorContext(ctx, "releasing idempotency key failed", "error", err)
+					}
+				}
+			}()
+
+			res := c.Response()
+			recorder := &bodyRecorder{ResponseWriter: res.Writer}
+			res.Writer = recorder
+			err = next(c)
+			if err != nil {
+				// Let the error handler write the response so that it is
+				// stored too.
+				c.Error(err)
+			}
+			res.Writer = recorder.ResponseWriter
+
+			if res.Status >= http.StatusInternalServerError {
+				return err
+			}
+			status := res.Status
+			record.StatusCode = &status
+			record.ContentType = res.Header().Get(echo.HeaderContentType)
+			record.Location = res.Header().Get(echo.HeaderLocation)
+			record.ETag = res.Header().Get(pkg.HeaderETag)
+			record.ResponseBody = recorder.body.Bytes()
+			if err := store.Complete(storeCtx, record); err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "storing idempotent response failed", "error", err)
+			} else {
+				completed = true
+			}
+			return err
+		}
+	}
+}
+
+// replay answers a request whose key is taken with the stored response.
+func replay(c echo.Context, store adapter.IdempotencyRepository, record *entity.IdempotencyKey) error {
+	stored, err := store.Get(c.Request().Context(), record.UserID, record.IdempotencyKey)
+	if errors.Is(err, entity.ErrIdempotencyKeyNotFound) {
+		// The first request failed and released the key in the meantime.
+		return entity.ErrIdempotencyKeyInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	if stored.Fingerprint != record.Fingerprint {
+		return entity.ErrIdempotencyKeyReused
+	}
+	if stored.StatusCode == nil {
+		c.Response().Header().Set("Retry-After", "1")
+		return entity.ErrIdempotencyKeyInUse
+	}
+
+	header := c.Response().Header()
+	header.Set(HeaderIdempotentReplayed, "true")
+	if stored.Location != "" {
+		header.Set(echo.HeaderLocation, stored.Location)
+	}
+	if stored.ETag != "" {
+		header.Set(pkg.HeaderETag, stored.ETag)
+	}
+	if len(stored.ResponseBody) == 0 {
+		return c.NoContent(*stored.StatusCode)
+	}
+	return c.Blob(*stored.StatusCode, stored.ContentType, stored.ResponseBody)
+}
+
+// fingerprint identifies a request by its method, path, query and body.
+func fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method)
+	h.Write([]byte{0})
+	io.WriteString(h, req.URL.RequestURI())
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func printableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyRecorder keeps a copy of the response body written through it.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
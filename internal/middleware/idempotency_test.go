@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/pkg"
+	"github.com/labstack/echo/v4"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyRepository.
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]entity.IdempotencyKey
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]entity.IdempotencyKey)}
+}
+
+func storeKey(userID uint, key string) string {
+	return fmt.Sprintf("%d/%s", userID, key)
+}
+
+func (s *memoryIdempotencyStore) Acquire(_ context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := storeKey(record.UserID, record.IdempotencyKey)
+	if old, ok := s.records[k]; ok && !old.ExpiresAt.Before(record.CreatedAt) && (old.StatusCode != nil || !old.CreatedAt.Before(staleBefore)) {
+		return false, nil
+	}
+	s.records[k] = *record
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Get(_ context.Context, userID uint, key string) (entity.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[storeKey(userID, key)]
+	if !ok {
+		return record, entity.ErrIdempotencyKeyNotFound
+	}
+	return record, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, record *entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[storeKey(record.UserID, record.IdempotencyKey)] = *record
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, record *entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, storeKey(record.UserID, record.IdempotencyKey))
+	return nil
+}
+
+func (s *memoryIdempotencyStore) PurgeExpired(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newIdempotencyFixture(store *memoryIdempotencyStore, handler echo.HandlerFunc) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = pkg.HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := auth.WithCaller(c.Request().Context(), auth.Caller{UserID: 7})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	})
+	e.Use(Idempotency(store, IdempotencyOptions{TTL: time.Hour, LockTimeout: time.Minute}))
+	e.POST("/orders", handler)
+	return e
+}
+
+func postOrder(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	calls := 0
+	e := newIdempotencyFixture(newMemoryIdempotencyStore(), func(c echo.Context) error {
+		calls++
+		return c.JSON(http.StatusCreated, map[string]int{"id": calls})
+	})
+
+	first := postOrder(e, "k1", `{"order_name":"a"}`)
+	second := postOrder(e, "k1", `{"order_name":"a"}`)
+
+	if calls != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected the first response to be replayed, got %d %s", second.Code, second.Body)
+	}
+	if second.Header().Get(HeaderIdempotentReplayed) != "true" || first.Header().Get(HeaderIdempotentReplayed) != "" {
+		t.Fatal("expected only the replay to be marked")
+	}
+
+	if rec := postOrder(e, "k1", `{"order_name":"b"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a different body to be rejected, got %d", rec.Code)
+	}
+	postOrder(e, "", `{"order_name":"a"}`)
+	postOrder(e, "", `{"order_name":"a"}`)
+	if calls != 3 {
+		t.Fatalf("expected requests without a key to run, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyReplaysLocationAndETag(t *testing.T) {
+	e := newIdempotencyFixture(newMemoryIdempotencyStore(), func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderLocation, "/orders/10")
+		c.Response().Header().Set(pkg.HeaderETag, `"1"`)
+		c.Response().Header().Set("X-Not-Replayed", "1")
+		return c.JSON(http.StatusCreated, map[string]int{"id": 10})
+	})
+
+	postOrder(e, "k1", `{"order_name":"a"}`)
+	second := postOrder(e, "k1", `{"order_name":"a"}`)
+
+	if got := second.Header().Get(echo.HeaderLocation); got != "/orders/10" {
+		t.Errorf("expected Location to be replayed, got %q", got)
+	}
+	if got := second.Header().Get(pkg.HeaderETag); got != `"1"` {
+		t.Errorf("expected ETag to be replayed, got %q", got)
+	}
+	if got := second.Header().Get("X-Not-Replayed"); got != "" {
+		t.Errorf("expected other headers not to be replayed, got %q", got)
+	}
+}
+
+func TestIdempotencyRejectsConcurrentDuplicate(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e := newIdempotencyFixture(store, func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.NoContent(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postOrder(e, "k1", `{}`) }()
+	<-started
+
+	if rec := postOrder(e, "k1", `{}`); rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected a duplicate in flight to get 409, got %d", rec.Code)
+	}
+	close(release)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Fatalf("expected the first request to complete, got %d", rec.Code)
+	}
+	if rec := postOrder(e, "k1", `{}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected the replay once completed, got %d", rec.Code)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	calls := 0
+	e := newIdempotencyFixture(newMemoryIdempotencyStore(), func(c echo.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	if rec := postOrder(e, "k1", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected the first attempt to fail, got %d", rec.Code)
+	}
+	if rec := postOrder(e, "k1", `{}`); rec.Code != http.StatusCreated || calls != 2 {
+		t.Fatalf("expected the retry to run, got %d after %d calls", rec.Code, calls)
+	}
+}
+
+func TestIdempotencyRejectsInvalidKey(t *testing.T) {
+	e := newIdempotencyFixture(newMemoryIdempotencyStore(), func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+
+	for _, key := range []string{"bad\x01key", strings.Repeat("k", 256)} {
+		if rec := postOrder(e, key, `{}`); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected key %q to be rejected, got %d", key, rec.Code)
+		}
+	}
+}
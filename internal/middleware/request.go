@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxRequestIDLength = 128
+
+// RequestLogger tags each request with an ID, taken from the X-Request-ID
+// header when it holds a usable one and generated otherwise, and echoes it in
+// the response. The request context carries a logger with the ID (and the
+// trace ID, when the request is traced) attached. One access log line is
+// written per request once it is done.
+func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			log := logger.With(slog.String("request_id", id))
+			if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+				log = log.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+			ctx := logging.WithRequestID(req.Context(), id)
+			ctx = logging.WithLogger(ctx, log)
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so that its
+				// status is the one logged.
+				c.Error(err)
+			}
+
+			res := c.Response()
+			attrs := []any{
+				slog.String("method", req.Method),
+				slog.String("route", c.Path()),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", res.Status),
+				slog.Int64("bytes", res.Size),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_ip", c.RealIP()),
+			}
+			if caller, ok := auth.CallerFromContext(c.Request().Context()); ok {
+				attrs = append(attrs, slog.Uint64("user_id", uint64(caller.UserID)))
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+
+			level := slog.LevelInfo
+			if res.Status >= 500 {
+				level = slog.LevelError
+			}
+			log.Log(ctx, level, "request", attrs...)
+			return err
+		}
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII only, so that a client
+// cannot inject line breaks or control characters into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	e := echo.New()
+	e.Use(RequestLogger(logger))
+	var seen string
+	e.GET("/orders/:id", func(c echo.Context) error {
+		seen, _ = logging.RequestIDFromContext(c.Request().Context())
+		return echo.NewHTTPError(http.StatusNotFound)
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"accepts the client's ID", "abc-123", true},
+		{"generates a missing ID", "", false},
+		{"replaces an ID with control characters", "abc\ndef", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+			if tc.header != "" {
+				req.Header.Set(echo.HeaderXRequestID, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			if tc.keep && id != tc.header {
+				t.Fatalf("expected the client's ID, got %q", id)
+			}
+			if !tc.keep && (id == "" || id == tc.header) {
+				t.Fatalf("expected a generated ID, got %q", id)
+			}
+			if seen != id {
+				t.Fatalf("expected the handler to see %q, got %q", id, seen)
+			}
+
+			var line struct {
+				Msg       string `json:"msg"`
+				RequestID string `json:"request_id"`
+				Route     string `json:"route"`
+				Status    int    `json:"status"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("expected one JSON access log line, got %q", buf.String())
+			}
+			if line.Msg != "request" || line.RequestID != id || line.Route != "/orders/:id" || line.Status != http.StatusNotFound {
+				t.Fatalf("unexpected access log %+v", line)
+			}
+		})
+	}
+}
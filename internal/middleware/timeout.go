@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// TimeoutConfig configures TimeoutWithConfig. Requests for which Skipper
+// returns true keep their context.
+type TimeoutConfig struct {
+	Skipper middleware.Skipper
+	Timeout time.Duration
+}
+
+// Timeout bounds the request context by d, so that services and queries
+// started by a handler are canceled once it has run for too long. A
+// non-positive d leaves the context unbounded.
+func Timeout(d time.Duration) echo.MiddlewareFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig is Timeout with a skipper, for routes that set their
+// own limit.
+func TimeoutWithConfig(config TimeoutConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if config.Timeout <= 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			ctx, cancel := context.WithTimeout(c.Request().Context(), config.Timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// ConnDeadline replaces the read and write deadlines the server set on the
+// connection with d from now, so that a route taking large uploads can
+// outlast server.read_timeout and server.write_timeout. A non-positive d
+// removes them.
+func ConnDeadline(d time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var deadline time.Time
+			if d > 0 {
+				deadline = time.Now().Add(d)
+			}
+			rc := http.NewResponseController(c.Response())
+			if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestTimeout(t *testing.T) {
+	e := echo.New()
+	e.Use(Timeout(time.Minute))
+	var deadline time.Time
+	var ok bool
+	e.GET("/", func(c echo.Context) error {
+		deadline, ok = c.Request().Context().Deadline()
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	start := time.Now()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !ok || deadline.Before(start.Add(time.Minute)) || deadline.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("expected a deadline a minute after the request, got %v, %v", deadline, ok)
+	}
+}
+
+func TestTimeoutSkipper(t *testing.T) {
+	e := echo.New()
+	e.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout: time.Minute,
+		Skipper: func(c echo.Context) bool { return c.Path() == "/imports" },
+	}))
+	var ok bool
+	e.POST("/imports", func(c echo.Context) error {
+		_, ok = c.Request().Context().Deadline()
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/imports", nil))
+	if ok {
+		t.Fatal("expected no deadline on a skipped route")
+	}
+}
+
+func TestConnDeadline(t *testing.T) {
+	echoBody := func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, string(body))
+	}
+	e := echo.New()
+	e.POST("/slow", echoBody)
+	e.POST("/imports", echoBody, ConnDeadline(time.Minute))
+
+	srv := httptest.NewUnstartedServer(e)
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// The body arrives after the server's read timeout.
+	send := func(path string) (*http.Response, error) {
+		body, w := io.Pipe()
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			w.Write([]byte("rows"))
+			w.Close()
+		}()
+		return http.Post(srv.URL+path, "text/csv", body)
+	}
+
+	res, err := send("/imports")
+	if err != nil {
+		t.Fatalf("POST /imports: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "rows" {
+		t.Fatalf("expected the body to be read past the read timeout, got %d %q", res.StatusCode, body)
+	}
+
+	if res, err := send("/slow"); err == nil {
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			t.Fatal("expected the read timeout to apply without ConnDeadline")
+		}
+	}
+}
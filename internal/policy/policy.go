@@ -0,0 +1,64 @@
+// Package policy decides whether the caller stored in a context may act on a
+// resource. Services consult it before reading or changing data; admins may
+// act on everything, other users only on their own account and orders.
+package policy
+
+import (
+	"context"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+var (
+	ErrUnauthenticated = apperror.Unauthorized("unauthenticated", "authentication is required")
+	ErrForbidden       = apperror.Forbidden("forbidden", "you are not allowed to access this resource")
+)
+
+// Caller returns the authenticated caller of ctx.
+func Caller(ctx context.Context) (auth.Caller, error) {
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return auth.Caller{}, ErrUnauthenticated
+	}
+	return caller, nil
+}
+
+// RequireAdmin allows admins only.
+func RequireAdmin(ctx context.Context) error {
+	caller, err := Caller(ctx)
+	if err != nil {
+		return err
+	}
+	if !caller.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeUser allows the user identified by userID and admins.
+func AuthorizeUser(ctx context.Context, userID uint) error {
+	caller, err := Caller(ctx)
+	if err != nil {
+		return err
+	}
+	if !caller.IsAdmin() && caller.UserID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeOrder allows the owner of order and admins.
+func AuthorizeOrder(ctx context.Context, order entity.Order) error {
+	return AuthorizeUser(ctx, order.UserID)
+}
+
+// AuthorizeTransition allows admins to move order to any status and its
+// owner to the statuses that are not set by the provider.
+func AuthorizeTransition(ctx context.Context, order entity.Order, to entity.OrderStatus) error {
+	if to.ProviderOnly() {
+		return RequireAdmin(ctx)
+	}
+	return AuthorizeOrder(ctx, order)
+}
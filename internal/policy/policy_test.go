@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+func TestAuthorizeOrder(t *testing.T) {
+	order := entity.Order{ID: 1, UserID: 7}
+
+	testCases := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"owner", auth.WithCaller(context.Background(), auth.Caller{UserID: 7, Role: entity.RoleUser}), nil},
+		{"other user", auth.WithCaller(context.Background(), auth.Caller{UserID: 8, Role: entity.RoleUser}), ErrForbidden},
+		{"admin", auth.WithCaller(context.Background(), auth.Caller{UserID: 8, Role: entity.RoleAdmin}), nil},
+		{"anonymous", context.Background(), ErrUnauthenticated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := AuthorizeOrder(tc.ctx, order); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	user := auth.WithCaller(context.Background(), auth.Caller{UserID: 1, Role: entity.RoleUser})
+	if err := RequireAdmin(user); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	admin := auth.WithCaller(context.Background(), auth.Caller{UserID: 1, Role: entity.RoleAdmin})
+	if err := RequireAdmin(admin); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
+
+func TestAuthorizeTransition(t *testing.T) {
+	order := entity.Order{ID: 1, UserID: 7}
+	owner := auth.WithCaller(context.Background(), auth.Caller{UserID: 7, Role: entity.RoleUser})
+	other := auth.WithCaller(context.Background(), auth.Caller{UserID: 8, Role: entity.RoleUser})
+	admin := auth.WithCaller(context.Background(), auth.Caller{UserID: 8, Role: entity.RoleAdmin})
+
+	testCases := []struct {
+		name string
+		ctx  context.Context
+		to   entity.OrderStatus
+		want error
+	}{
+		{"owner submits", owner, entity.OrderStatusSubmitted, nil},
+		{"owner cancels", owner, entity.OrderStatusCancelled, nil},
+		{"owner disputes", owner, entity.OrderStatusDisputed, nil},
+		{"owner accepts", owner, entity.OrderStatusAccepted, ErrForbidden},
+		{"owner starts work", owner, entity.OrderStatusInProgress, ErrForbidden},
+		{"owner completes", owner, entity.OrderStatusCompleted, ErrForbidden},
+		{"other user submits", other, entity.OrderStatusSubmitted, ErrForbidden},
+		{"admin accepts", admin, entity.OrderStatusAccepted, nil},
+		{"admin completes", admin, entity.OrderStatusCompleted, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := AuthorizeTransition(tc.ctx, order, tc.to); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidCredentials = apperror.Unauthorized("invalid_credentials", "email or password is incorrect")
+
+type AuthService interface {
+	Register(ctx context.Context, req api.Register) (entity.User, error)
+	Login(ctx context.Context, email, password string) (auth.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (auth.TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
+	EnsureAdmin(ctx context.Context, name, email, password string) error
+}
+
+type authService struct {
+	userRepo     adapter.UserRepository
+	refreshRepo  adapter.RefreshTokenRepository
+	cacheManager adapter.CacheManager
+	tokens       *auth.TokenManager
+}
+
+// NewAuthService returns an AuthService. Active refresh tokens are kept in
+// refreshRepo rather than the cache, so that issuing, consuming and revoking
+// them fails instead of silently doing nothing while the cache is down.
+func NewAuthService(
+	userRepo adapter.UserRepository,
+	refreshRepo adapter.RefreshTokenRepository,
+	cacheManager adapter.CacheManager,
+	tokens *auth.TokenManager,
+) AuthService {
+	return &authService{
+		userRepo:     userRepo,
+		refreshRepo:  refreshRepo,
+		cacheManager: cacheManager,
+		tokens:       tokens,
+	}
+}
+
+func (s *authService) Register(ctx context.Context, req api.Register) (entity.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user := entity.User{
+		Name:         req.Name,
+		Email:        entity.NormalizeEmail(req.Email),
+		PasswordHash: string(hash),
+		Role:         entity.RoleUser,
+	}
+	if err := s.userRepo.Create(ctx, &user); err != nil {
+		return entity.User{}, err
+	}
+
+	var inv cacheInvalidation
+	inv.user(user.ID)
+	inv.flush(ctx, s.cacheManager)
+
+	return user, nil
+}
+
+func (s *authService) Login(ctx context.Context, email, password string) (auth.TokenPair, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, entity.ErrUserNotFound) {
+		return auth.TokenPair{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return auth.TokenPair{}, err
+	}
+
+	if user.PasswordHash == "" {
+		return auth.TokenPair{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return auth.TokenPair{}, ErrInvalidCredentials
+	}
+
+	return s.issue(ctx, user)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The old refresh
+// token is revoked, so each one can be used only once.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (auth.TokenPair, error) {
+	claims, err := s.tokens.Parse(refreshToken, auth.RefreshToken)
+	if err != nil {
+		return auth.TokenPair{}, err
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return auth.TokenPair{}, err
+	}
+
+	// The token is consumed in one statement, so of concurrent refreshes
+	// with the same token only one gets a new pair.
+	activeUserID, ok, err := s.refreshRepo.Consume(ctx, claims.ID, time.Now())
+	if err != nil {
+		return auth.TokenPair{}, err
+	}
+	if !ok || activeUserID != userID {
+		return auth.TokenPair{}, auth.ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrUserNotFound) {
+			return auth.TokenPair{}, auth.ErrInvalidToken
+		}
+		return auth.TokenPair{}, err
+	}
+
+	return s.issue(ctx, user)
+}
+
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.tokens.Parse(refreshToken, auth.RefreshToken)
+	if err != nil {
+		return err
+	}
+	return s.refreshRepo.Delete(ctx, claims.ID)
+}
+
+// EnsureAdmin creates an admin account with the given credentials unless a
+// user with that email already exists. An existing account is never promoted.
+func (s *authService) EnsureAdmin(ctx context.Context, name, email, password string) error {
+	_, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, entity.ErrUserNotFound) {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	admin := entity.User{
+		Name:         name,
+		Email:        entity.NormalizeEmail(email),
+		PasswordHash: string(hash),
+		Role:         entity.RoleAdmin,
+	}
+	if err := s.userRepo.Create(ctx, &admin); err != nil {
+		return err
+	}
+
+	var inv cacheInvalidation
+	inv.user(admin.ID)
+	inv.flush(ctx, s.cacheManager)
+	return nil
+}
+
+func (s *authService) issue(ctx context.Context, user entity.User) (auth.TokenPair, error) {
+	pair, refreshID, err := s.tokens.Issue(user.ID, user.Role)
+	if err != nil {
+		return auth.TokenPair{}, err
+	}
+
+	if err := s.refreshRepo.Create(ctx, refreshID, user.ID, pair.RefreshExpiresAt); err != nil {
+		return auth.TokenPair{}, err
+	}
+
+	return pair, nil
+}
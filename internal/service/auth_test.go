@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+type fakeRefreshTokenRepo struct {
+	adapter.RefreshTokenRepository
+	mu     sync.Mutex
+	tokens map[string]uint
+	err    error
+}
+
+func (r *fakeRefreshTokenRepo) Create(_ context.Context, id string, userID uint, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.tokens[id] = userID
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) Consume(_ context.Context, id string, _ time.Time) (uint, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0, false, r.err
+	}
+	userID, ok := r.tokens[id]
+	delete(r.tokens, id)
+	return userID, ok, nil
+}
+
+func (r *fakeRefreshTokenRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tokens, id)
+	return r.err
+}
+
+func newAuthFixture(t *testing.T) (AuthService, *fakeRefreshTokenRepo, *auth.TokenManager) {
+	f := newCacheFixture(t)
+	refreshRepo := &fakeRefreshTokenRepo{tokens: make(map[string]uint)}
+	tokens := auth.NewTokenManager("secret", "test", time.Minute, time.Hour)
+	return NewAuthService(f.userRepo, refreshRepo, f.cache, tokens), refreshRepo, tokens
+}
+
+func TestRefreshTokenIsUsableOnce(t *testing.T) {
+	auths, refreshRepo, tokens := newAuthFixture(t)
+	pair, refreshID, err := tokens.Issue(1, entity.RoleUser)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	refreshRepo.tokens[refreshID] = 1
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := auths.Refresh(context.Background(), pair.RefreshToken)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("Refresh: got %v, want an invalid token", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d refreshes succeeded with the same token, want 1", succeeded)
+	}
+}
+
+func TestRefreshTokenStoreErrorsAreReturned(t *testing.T) {
+	auths, refreshRepo, tokens := newAuthFixture(t)
+	pair, _, err := tokens.Issue(1, entity.RoleUser)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	refreshRepo.err = errors.New("connection refused")
+
+	if _, err := auths.Refresh(context.Background(), pair.RefreshToken); !errors.Is(err, refreshRepo.err) {
+		t.Errorf("Refresh: got %v, want the store error", err)
+	}
+	if err := auths.Logout(context.Background(), pair.RefreshToken); !errors.Is(err, refreshRepo.err) {
+		t.Errorf("Logout: got %v, want the store error", err)
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+)
+
+// Cache tags group the list pages that embed a kind of entity. User pages
+// embed the users' orders and order pages embed the order's user, so a change
+// to either entity makes both tags stale.
+const (
+	usersCacheTag  = "users"
+	ordersCacheTag = "orders"
+)
+
+// tagVersionTTL keeps tag versions much longer than the pages they guard;
+// losing a version only makes the current pages unreachable.
+const tagVersionTTL = 24 * time.Hour
+
+func userCacheKey(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+func orderCacheKey(id uint) string {
+	return fmt.Sprintf("order:%d", id)
+}
+
+func tagVersionKey(tag string) string {
+	return tag + ":version"
+}
+
+// listCacheKey builds the cache key of a single list page for the given
+// filter. The key embeds the current version of tag so that bumping the tag
+// drops every cached page at once. A missing version is replaced by a new
+// one rather than treated as empty, so deleting the version key, as the
+// circuit breaker does after an outage, also drops the pages.
+func listCacheKey(ctx context.Context, cacheManager adapter.CacheManager, tag string, filter interface{}, page adapter.PageQuery) string {
+	var version string
+	if err := cacheManager.Get(ctx, tagVersionKey(tag), &version); err != nil {
+		version = newTagVersion()
+		if errors.Is(err, adapter.ErrCacheMiss) {
+			_ = cacheManager.Set(ctx, tagVersionKey(tag), version, tagVersionTTL)
+		}
+	}
+
+	params, _ := json.Marshal(struct {
+		Filter interface{}       `json:"filter"`
+		Page   adapter.PageQuery `json:"page"`
+	}{filter, page})
+	sum := sha256.Sum256(params)
+
+	return fmt.Sprintf("%s:list:v%s:%s", tag, version, hex.EncodeToString(sum[:]))
+}
+
+func newTagVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
+// cacheInvalidation collects the cache entries made stale by a write. Services
+// fill it while the write runs and flush it once the transaction committed;
+// invalidating earlier would let a concurrent read cache the old rows again.
+type cacheInvalidation struct {
+	keys []string
+	tags []string
+}
+
+// user marks the cached user and every list page embedding users as stale.
+// Orders embedding the user must be added with order.
+func (inv *cacheInvalidation) user(id uint) {
+	inv.keys = append(inv.keys, userCacheKey(id))
+	inv.tags = append(inv.tags, usersCacheTag, ordersCacheTag)
+}
+
+// order marks the cached order, the cached detail of its owner, which embeds
+// its orders, and every list page embedding orders as stale.
+func (inv *cacheInvalidation) order(id, userID uint) {
+	inv.keys = append(inv.keys, orderCacheKey(id), userCacheKey(userID))
+	inv.tags = append(inv.tags, usersCacheTag, ordersCacheTag)
+}
+
+// flush deletes the collected keys and bumps the collected tags. The write
+// has committed by then, so flush runs without the request's cancellation,
+// which would otherwise leave stale entries behind when the client goes
+// away, and logs failures rather than failing the write.
+func (inv *cacheInvalidation) flush(ctx context.Context, cacheManager adapter.CacheManager) {
+	ctx = context.WithoutCancel(ctx)
+
+	keys := make([]string, 0, len(inv.keys))
+	seen := make(map[string]bool)
+	for _, key := range inv.keys {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if err := cacheManager.Delete(ctx, keys...); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "cache delete failed", "keys", keys, "error", err)
+	}
+
+	version := newTagVersion()
+	bumped := make(map[string]bool)
+	for _, tag := range inv.tags {
+		if bumped[tag] {
+			continue
+		}
+		bumped[tag] = true
+		if err := cacheManager.Set(ctx, tagVersionKey(tag), version, tagVersionTTL); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "cache tag bump failed", "tag", tag, "error", err)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func cached(cacheManager adapter.CacheManager, key string) bool {
+	var raw interface{}
+	return cacheManager.Get(context.Background(), key, &raw) == nil
+}
+
+// dryRunDB builds statements without executing them, so the transactional
+// parts of the services run without a database.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open dry run db: %v", err)
+	}
+	return db
+}
+
+type fakeUserRepo struct {
+	adapter.UserRepository
+	db       *gorm.DB
+	users    map[uint]entity.User
+	getCalls map[uint]int
+	txErr    error
+	inTx     func()
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uint) (entity.User, error) {
+	r.getCalls[id]++
+	user, ok := r.users[id]
+	if !ok {
+		return entity.User{}, entity.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepo) ExistingIDs(_ context.Context, ids []uint) (map[uint]bool, error) {
+	existing := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := r.users[id]; ok {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+func (r *fakeUserRepo) IDsByEmail(_ context.Context, emails []string) (map[string]uint, error) {
+	ids := make(map[string]uint)
+	for _, email := range emails {
+		for _, user := range r.users {
+			if strings.EqualFold(user.Email, email) {
+				ids[strings.ToLower(email)] = user.ID
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeUserRepo) CreateBatch(_ context.Context, users []entity.User) error {
+	for i := range users {
+		users[i].ID = uint(len(r.users) + 1)
+		r.users[users[i].ID] = users[i]
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) Transaction(_ context.Context, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+	if r.inTx != nil {
+		r.inTx()
+	}
+	if r.txErr != nil {
+		return r.txErr
+	}
+	return fc(r.db)
+}
+
+type fakeOrderRepo struct {
+	adapter.OrderRepository
+	db     *gorm.DB
+	orders map[uint]entity.Order
+}
+
+func (r *fakeOrderRepo) GetByID(_ context.Context, id uint) (entity.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return entity.Order{}, entity.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (r *fakeOrderRepo) IDsByUser(_ context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	for _, order := range r.orders {
+		if order.UserID == userID {
+			ids = append(ids, order.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeOrderRepo) Delete(_ context.Context, order *entity.Order) error {
+	delete(r.orders, order.ID)
+	return nil
+}
+
+func (r *fakeOrderRepo) Transaction(_ context.Context, fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+	return fc(r.db)
+}
+
+type cacheFixture struct {
+	cache     adapter.CacheManager
+	userRepo  *fakeUserRepo
+	orderRepo *fakeOrderRepo
+	users     UserService
+	orders    OrderService
+}
+
+func newCacheFixture(t *testing.T) *cacheFixture {
+	db := dryRunDB(t)
+	alice := entity.User{ID: 1, Name: "Alice", Email: "alice@example.com"}
+	bob := entity.User{ID: 2, Name: "Bob", Email: "bob@example.com"}
+
+	f := &cacheFixture{
+		cache: adapter.NewMemoryCache(adapter.MemoryCacheOptions{}, adapter.JSONCodec),
+		userRepo: &fakeUserRepo{
+			db:       db,
+			users:    map[uint]entity.User{1: alice, 2: bob},
+			getCalls: make(map[uint]int),
+		},
+		orderRepo: &fakeOrderRepo{
+			db: db,
+			orders: map[uint]entity.Order{
+				10: {ID: 10, OrderName: "Logo", UserID: 1, User: alice},
+				11: {ID: 11, OrderName: "Website", UserID: 1, User: alice},
+				20: {ID: 20, OrderName: "Banner", UserID: 2, User: bob},
+			},
+		},
+	}
+	f.users = NewUserService(f.userRepo, f.orderRepo, f.cache, adapter.RefreshPolicy{TTL: time.Minute})
+	f.orders = NewOrderService(f.orderRepo, f.userRepo, f.cache, adapter.RefreshPolicy{TTL: time.Minute})
+	return f
+}
+
+func adminContext() context.Context {
+	return auth.WithCaller(context.Background(), auth.Caller{UserID: 99, Role: entity.RoleAdmin})
+}
+
+func TestGetUserByIDCachesPerUser(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	for _, id := range []uint{1, 2, 1, 2} {
+		user, err := f.users.GetUserByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetUserByID(%d): %v", id, err)
+		}
+		if user.ID != id {
+			t.Fatalf("GetUserByID(%d) returned user %d", id, user.ID)
+		}
+	}
+
+	if f.userRepo.getCalls[1] != 1 || f.userRepo.getCalls[2] != 1 {
+		t.Fatalf("expected one repository read per user, got %v", f.userRepo.getCalls)
+	}
+}
+
+func TestUpdateUserInvalidatesUserAndEmbeddingOrders(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	for _, id := range []uint{1, 2} {
+		if _, err := f.users.GetUserByID(ctx, id); err != nil {
+			t.Fatalf("GetUserByID(%d): %v", id, err)
+		}
+	}
+	for _, id := range []uint{10, 11, 20} {
+		if _, err := f.orders.GetOrderByID(ctx, id); err != nil {
+			t.Fatalf("GetOrderByID(%d): %v", id, err)
+		}
+	}
+	ordersList := listCacheKey(ctx, f.cache, ordersCacheTag, adapter.OrderFilter{}, adapter.PageQuery{})
+
+	if _, err := f.users.UpdateUser(ctx, 1, nil, "Alice Smith", "alice@example.com"); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	for _, key := range []string{userCacheKey(1), orderCacheKey(10), orderCacheKey(11)} {
+		if cached(f.cache, key) {
+			t.Errorf("expected %s to be invalidated", key)
+		}
+	}
+	for _, key := range []string{userCacheKey(2), orderCacheKey(20)} {
+		if !cached(f.cache, key) {
+			t.Errorf("expected %s to stay cached", key)
+		}
+	}
+	if listCacheKey(ctx, f.cache, ordersCacheTag, adapter.OrderFilter{}, adapter.PageQuery{}) == ordersList {
+		t.Errorf("expected the orders list key to change")
+	}
+}
+
+func TestInvalidationHappensAfterCommit(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	cachedDuringTx := false
+	f.userRepo.inTx = func() { cachedDuringTx = cached(f.cache, userCacheKey(1)) }
+
+	if _, err := f.users.UpdateUser(ctx, 1, nil, "Alice Smith", "alice@example.com"); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if !cachedDuringTx {
+		t.Fatalf("expected the cache to be invalidated after the transaction, not before")
+	}
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	f.userRepo.inTx = nil
+	f.userRepo.txErr = errors.New("rollback")
+
+	if _, err := f.users.UpdateUser(ctx, 1, nil, "Alice Smith", "alice@example.com"); err == nil {
+		t.Fatalf("expected UpdateUser to fail")
+	}
+	if !cached(f.cache, userCacheKey(1)) {
+		t.Fatalf("expected a rolled back update to keep the cache")
+	}
+}
+
+func TestCreateOrderInvalidatesOwnerNotOrderWithSameID(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+	f.orderRepo.orders[1] = entity.Order{ID: 1, OrderName: "Poster", UserID: 2}
+
+	if _, err := f.orders.GetOrderByID(ctx, 1); err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	if _, err := f.orders.CreateOrder(ctx, api.CreateOrder{OrderName: "Flyer", UserID: 1}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	if !cached(f.cache, orderCacheKey(1)) {
+		t.Errorf("expected order 1 to stay cached")
+	}
+	if cached(f.cache, userCacheKey(1)) {
+		t.Errorf("expected the owner to be invalidated")
+	}
+}
+
+func TestDeleteOrderInvalidatesOrderAndOwner(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	if _, err := f.orders.GetOrderByID(ctx, 20); err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+	if _, err := f.users.GetUserByID(ctx, 2); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	usersList := listCacheKey(ctx, f.cache, usersCacheTag, adapter.UserFilter{}, adapter.PageQuery{})
+
+	if err := f.orders.DeleteOrder(ctx, 20, nil); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+
+	if cached(f.cache, orderCacheKey(20)) || cached(f.cache, userCacheKey(2)) {
+		t.Fatalf("expected order 20 and its owner to be invalidated")
+	}
+	if listCacheKey(ctx, f.cache, usersCacheTag, adapter.UserFilter{}, adapter.PageQuery{}) == usersList {
+		t.Fatalf("expected the users list key to change")
+	}
+}
+
+// ctxCache fails like Redis does: with the context's error once it is done,
+// and with err otherwise.
+type ctxCache struct {
+	adapter.CacheManager
+	err error
+}
+
+func (c ctxCache) Delete(ctx context.Context, keys ...string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if c.err != nil {
+		return c.err
+	}
+	return c.CacheManager.Delete(ctx, keys...)
+}
+
+func TestInvalidationOutlivesTheRequest(t *testing.T) {
+	f := newCacheFixture(t)
+	f.users = NewUserService(f.userRepo, f.orderRepo, ctxCache{CacheManager: f.cache}, adapter.RefreshPolicy{TTL: time.Minute})
+	ctx, cancel := context.WithCancel(adminContext())
+	defer cancel()
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	// The client goes away while the update commits.
+	f.userRepo.inTx = cancel
+
+	if _, err := f.users.UpdateUser(ctx, 1, nil, "Alice Smith", "alice@example.com"); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if cached(f.cache, userCacheKey(1)) {
+		t.Fatalf("expected the user to be invalidated after the request was canceled")
+	}
+}
+
+func TestFailedInvalidationKeepsTheWrite(t *testing.T) {
+	f := newCacheFixture(t)
+	f.users = NewUserService(f.userRepo, f.orderRepo, ctxCache{CacheManager: f.cache, err: errors.New("READONLY")}, adapter.RefreshPolicy{TTL: time.Minute})
+
+	user, err := f.users.UpdateUser(adminContext(), 1, nil, "Alice Smith", "alice@example.com")
+	if err != nil {
+		t.Fatalf("expected the committed update to succeed, got %v", err)
+	}
+	if user.Name != "Alice Smith" {
+		t.Fatalf("got user %+v", user)
+	}
+}
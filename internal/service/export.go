@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/exporter"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
+)
+
+// ExportService exports the orders matching a list filter, either straight
+// to a writer or through export jobs that write a file in the background.
+type ExportService interface {
+	// ExportOrders writes the orders matching filter to w. It fails with
+	// entity.ErrExportTooLarge before writing anything when more than the
+	// configured number of orders match.
+	ExportOrders(ctx context.Context, filter adapter.OrderFilter, format exporter.Format, w io.Writer) error
+	CreateExportJob(ctx context.Context, filter adapter.OrderFilter, format exporter.Format) (entity.ExportJob, error)
+	GetExportJob(ctx context.Context, id uint) (entity.ExportJob, error)
+	// ExportFile returns a completed job and the path of its file.
+	ExportFile(ctx context.Context, id uint) (entity.ExportJob, string, error)
+	// RunJobs runs the pending jobs one after the other, until there are
+	// none left, and removes the expired ones.
+	RunJobs(ctx context.Context) error
+	// Run calls RunJobs every interval and whenever a job is created, until
+	// ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// ExportOptions configures an ExportService. Job files are written to Dir
+// and kept for TTL. A job running longer than JobTimeout is given up and
+// may be taken over by another worker.
+type ExportOptions struct {
+	Dir         string
+	MaxSyncRows int64
+	BatchSize   int
+	TTL         time.Duration
+	JobTimeout  time.Duration
+}
+
+type exportService struct {
+	orderRepo adapter.OrderRepository
+	jobRepo   adapter.ExportJobRepository
+	opts      ExportOptions
+
+	// created wakes Run up when a job is created on this instance.
+	created chan struct{}
+}
+
+func NewExportService(orderRepo adapter.OrderRepository, jobRepo adapter.ExportJobRepository, opts ExportOptions) ExportService {
+	return &exportService{
+		orderRepo: orderRepo,
+		jobRepo:   jobRepo,
+		opts:      opts,
+		created:   make(chan struct{}, 1),
+	}
+}
+
+func (s *exportService) ExportOrders(ctx context.Context, filter adapter.OrderFilter, format exporter.Format, w io.Writer) error {
+	if err := authorizeOrderFilter(ctx, filter); err != nil {
+		return err
+	}
+
+	count, err := s.orderRepo.Count(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count > s.opts.MaxSyncRows {
+		return entity.ErrExportTooLarge
+	}
+
+	_, err = s.writeOrders(ctx, filter, format, w)
+	return err
+}
+
+// writeOrders writes the orders matching filter to w and returns how many
+// were written.
+func (s *exportService) writeOrders(ctx context.Context, filter adapter.OrderFilter, format exporter.Format, w io.Writer) (int64, error) {
+	out, err := exporter.NewWriter[adapter.OrderExportRow](w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	err = s.orderRepo.Export(ctx, filter, s.opts.BatchSize, func(rows []adapter.OrderExportRow) error {
+		for _, row := range rows {
+			if err := out.Write(row); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	return written, out.Close()
+}
+
+func (s *exportService) CreateExportJob(ctx context.Context, filter adapter.OrderFilter, format exporter.Format) (entity.ExportJob, error) {
+	if err := authorizeOrderFilter(ctx, filter); err != nil {
+		return entity.ExportJob{}, err
+	}
+	caller, err := policy.Caller(ctx)
+	if err != nil {
+		return entity.ExportJob{}, err
+	}
+
+	if format == exporter.FormatXLSX {
+		count, err := s.orderRepo.Count(ctx, filter)
+		if err != nil {
+			return entity.ExportJob{}, err
+		}
+		if count >= exporter.MaxXLSXRows {
+			return entity.ExportJob{}, entity.ErrExportTooLargeXLSX
+		}
+	}
+
+	rawFilter, err := json.Marshal(filter)
+	if err != nil {
+		return entity.ExportJob{}, err
+	}
+	job := entity.ExportJob{
+		UserID: caller.UserID,
+		Format: string(format),
+		Filter: rawFilter,
+		Status: entity.ExportJobPending,
+	}
+	if err := s.jobRepo.Create(ctx, &job); err != nil {
+		return entity.ExportJob{}, err
+	}
+
+	select {
+	case s.created <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (s *exportService) GetExportJob(ctx context.Context, id uint) (entity.ExportJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return entity.ExportJob{}, err
+	}
+	if err := policy.AuthorizeUser(ctx, job.UserID); err != nil {
+		return entity.ExportJob{}, err
+	}
+	return job, nil
+}
+
+func (s *exportService) ExportFile(ctx context.Context, id uint) (entity.ExportJob, string, error) {
+	job, err := s.GetExportJob(ctx, id)
+	if err != nil {
+		return entity.ExportJob{}, "", err
+	}
+	if job.Status != entity.ExportJobCompleted {
+		return entity.ExportJob{}, "", entity.ErrExportNotReady
+	}
+	if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+		return entity.ExportJob{}, "", entity.ErrExportFileExpired
+	}
+
+	path := filepath.Join(s.opts.Dir, job.FileName)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return entity.ExportJob{}, "", entity.ErrExportFileExpired
+	} else if err != nil {
+		return entity.ExportJob{}, "", err
+	}
+	return job, path, nil
+}
+
+func (s *exportService) RunJobs(ctx context.Context) error {
+	if err := s.purgeExpired(ctx); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		now := time.Now()
+		job, ok, err := s.jobRepo.Claim(ctx, now, now.Add(-s.opts.JobTimeout))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		s.runJob(ctx, job)
+	}
+	return ctx.Err()
+}
+
+// runJob writes the file of a claimed job and records the outcome. The file
+// is written under a temporary name and renamed once complete, so a
+// download never sees half a file.
+func (s *exportService) runJob(ctx context.Context, job entity.ExportJob) {
+	log := logging.FromContext(ctx).With("export_job_id", job.ID)
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.opts.JobTimeout)
+	defer cancel()
+
+	fileName := fmt.Sprintf("orders-%d%s", job.ID, exporter.Format(job.Format).Extension())
+	rows, err := s.writeJobFile(jobCtx, job, fileName)
+	if ctx.Err() != nil {
+		// Shutting down: the job stays running and is taken over once it
+		// times out.
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.opts.TTL)
+	job.Rows = rows
+	job.CompletedAt = &now
+	job.ExpiresAt = &expiresAt
+	if err != nil {
+		log.ErrorContext(ctx, "export job failed", "error", err)
+		job.Status = entity.ExportJobFailed
+		job.Error = exportJobError(err)
+	} else {
+		job.Status = entity.ExportJobCompleted
+		job.FileName = fileName
+	}
+
+	if err := s.jobRepo.Finish(ctx, &job); err != nil {
+		log.ErrorContext(ctx, "recording the export job outcome failed", "error", err)
+		return
+	}
+	log.InfoContext(ctx, "export job finished", "status", job.Status, "rows", job.Rows)
+}
+
+func (s *exportService) writeJobFile(ctx context.Context, job entity.ExportJob, fileName string) (int64, error) {
+	var filter adapter.OrderFilter
+	if err := json.Unmarshal(job.Filter, &filter); err != nil {
+		return 0, fmt.Errorf("decoding the filter: %w", err)
+	}
+
+	if err := os.MkdirAll(s.opts.Dir, 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(s.opts.Dir, fileName+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	rows, err := s.writeOrders(ctx, filter, exporter.Format(job.Format), tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, os.Rename(tmp.Name(), filepath.Join(s.opts.Dir, fileName))
+}
+
+// exportJobError is the error shown to the owner of a failed job. Causes
+// other than the format limits are internal, so they are only logged.
+func exportJobError(err error) string {
+	switch {
+	case errors.Is(err, exporter.ErrTooManyRows):
+		return entity.ErrExportTooLargeXLSX.Message
+	case errors.Is(err, context.DeadlineExceeded):
+		return "the export took too long"
+	}
+	return "the export failed"
+}
+
+// purgeExpired removes the expired jobs and their files.
+func (s *exportService) purgeExpired(ctx context.Context) error {
+	jobs, err := s.jobRepo.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.FileName == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.opts.Dir, job.FileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logging.FromContext(ctx).ErrorContext(ctx, "removing an expired export file failed", "export_job_id", job.ID, "error", err)
+		}
+	}
+	if len(jobs) > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "purged expired export jobs", "jobs", len(jobs))
+	}
+	return nil
+}
+
+func (s *exportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunJobs(ctx); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "running export jobs failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.created:
+		}
+	}
+}
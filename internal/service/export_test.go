@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/exporter"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
+)
+
+// exportOrderRepo serves rows to Export, filtered by user only.
+type exportOrderRepo struct {
+	adapter.OrderRepository
+	rows      []adapter.OrderExportRow
+	exportErr error
+	batches   int
+}
+
+func (r *exportOrderRepo) matching(filter adapter.OrderFilter) []adapter.OrderExportRow {
+	var rows []adapter.OrderExportRow
+	for _, row := range r.rows {
+		if filter.UserID == nil || row.UserID == *filter.UserID {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (r *exportOrderRepo) Count(_ context.Context, filter adapter.OrderFilter) (int64, error) {
+	return int64(len(r.matching(filter))), nil
+}
+
+func (r *exportOrderRepo) Export(_ context.Context, filter adapter.OrderFilter, batchSize int, fn func([]adapter.OrderExportRow) error) error {
+	if r.exportErr != nil {
+		return r.exportErr
+	}
+	rows := r.matching(filter)
+	for len(rows) > 0 {
+		n := min(batchSize, len(rows))
+		r.batches++
+		if err := fn(rows[:n]); err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+type fakeExportJobRepo struct {
+	adapter.ExportJobRepository
+	jobs map[uint]entity.ExportJob
+}
+
+func (r *fakeExportJobRepo) Create(_ context.Context, job *entity.ExportJob) error {
+	job.ID = uint(len(r.jobs) + 1)
+	job.CreatedAt = time.Now()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *fakeExportJobRepo) GetByID(_ context.Context, id uint) (entity.ExportJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return entity.ExportJob{}, entity.ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+func (r *fakeExportJobRepo) Claim(_ context.Context, now, _ time.Time) (entity.ExportJob, bool, error) {
+	for id := uint(1); id <= uint(len(r.jobs)); id++ {
+		job, ok := r.jobs[id]
+		if ok && job.Status == entity.ExportJobPending {
+			job.Status = entity.ExportJobRunning
+			job.StartedAt = &now
+			r.jobs[id] = job
+			return job, true, nil
+		}
+	}
+	return entity.ExportJob{}, false, nil
+}
+
+func (r *fakeExportJobRepo) Finish(_ context.Context, job *entity.ExportJob) error {
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *fakeExportJobRepo) PurgeExpired(_ context.Context, now time.Time) ([]entity.ExportJob, error) {
+	var purged []entity.ExportJob
+	for id, job := range r.jobs {
+		if job.ExpiresAt != nil && job.ExpiresAt.Before(now) {
+			purged = append(purged, job)
+			delete(r.jobs, id)
+		}
+	}
+	return purged, nil
+}
+
+type exportFixture struct {
+	orderRepo *exportOrderRepo
+	jobRepo   *fakeExportJobRepo
+	dir       string
+	exports   ExportService
+}
+
+func newExportFixture(t *testing.T) *exportFixture {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	f := &exportFixture{
+		orderRepo: &exportOrderRepo{rows: []adapter.OrderExportRow{
+			{ID: 10, OrderName: "Logo", UserID: 1, UserName: "Alice", UserEmail: "alice@example.com", CreatedAt: at, UpdatedAt: at},
+			{ID: 11, OrderName: "Website", UserID: 1, UserName: "Alice", UserEmail: "alice@example.com", CreatedAt: at, UpdatedAt: at},
+			{ID: 20, OrderName: "Banner", UserID: 2, UserName: "Bob", UserEmail: "bob@example.com", CreatedAt: at, UpdatedAt: at},
+		}},
+		jobRepo: &fakeExportJobRepo{jobs: make(map[uint]entity.ExportJob)},
+		dir:     t.TempDir(),
+	}
+	f.exports = NewExportService(f.orderRepo, f.jobRepo, ExportOptions{
+		Dir:         f.dir,
+		MaxSyncRows: 2,
+		BatchSize:   1,
+		TTL:         time.Hour,
+		JobTimeout:  time.Minute,
+	})
+	return f
+}
+
+func aliceContext() context.Context {
+	return auth.WithCaller(context.Background(), auth.Caller{UserID: 1, Role: entity.RoleUser})
+}
+
+func TestExportOrders(t *testing.T) {
+	f := newExportFixture(t)
+	alice := uint(1)
+
+	var out bytes.Buffer
+	if err := f.exports.ExportOrders(aliceContext(), adapter.OrderFilter{UserID: &alice}, exporter.FormatCSV, &out); err != nil {
+		t.Fatalf("ExportOrders: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id,order_name,") || !strings.Contains(lines[2], "Website,") {
+		t.Errorf("got:\n%s", out.String())
+	}
+	if f.orderRepo.batches != 2 {
+		t.Errorf("got %d batches, want 2", f.orderRepo.batches)
+	}
+}
+
+func TestExportOrdersChecksAccessAndSize(t *testing.T) {
+	f := newExportFixture(t)
+	bob := uint(2)
+
+	for name, tc := range map[string]struct {
+		ctx    context.Context
+		filter adapter.OrderFilter
+		want   error
+	}{
+		"all orders as a user":   {aliceContext(), adapter.OrderFilter{}, policy.ErrForbidden},
+		"other user's orders":    {aliceContext(), adapter.OrderFilter{UserID: &bob}, policy.ErrForbidden},
+		"too many for an export": {adminContext(), adapter.OrderFilter{}, entity.ErrExportTooLarge},
+	} {
+		var out bytes.Buffer
+		err := f.exports.ExportOrders(tc.ctx, tc.filter, exporter.FormatCSV, &out)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tc.want)
+		}
+		if out.Len() > 0 {
+			t.Errorf("%s: wrote %q", name, out.String())
+		}
+	}
+}
+
+func TestExportJob(t *testing.T) {
+	f := newExportFixture(t)
+	ctx := adminContext()
+
+	job, err := f.exports.CreateExportJob(ctx, adapter.OrderFilter{}, exporter.FormatNDJSON)
+	if err != nil {
+		t.Fatalf("CreateExportJob: %v", err)
+	}
+	if job.Status != entity.ExportJobPending || job.UserID != 99 {
+		t.Fatalf("got job %+v", job)
+	}
+	if _, _, err := f.exports.ExportFile(ctx, job.ID); !errors.Is(err, entity.ErrExportNotReady) {
+		t.Fatalf("ExportFile before the job ran: got %v, want not ready", err)
+	}
+
+	if err := f.exports.RunJobs(context.Background()); err != nil {
+		t.Fatalf("RunJobs: %v", err)
+	}
+
+	job, path, err := f.exports.ExportFile(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("ExportFile: %v", err)
+	}
+	if job.Status != entity.ExportJobCompleted || job.Rows != 3 || job.ExpiresAt == nil {
+		t.Errorf("got job %+v", job)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read export file: %v", err)
+	}
+	if strings.Count(string(content), "\n") != 3 || !strings.Contains(string(content), `"user_email":"bob@example.com"`) {
+		t.Errorf("got file:\n%s", content)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(f.dir, "*.tmp")); len(tmp) > 0 {
+		t.Errorf("temporary files left: %v", tmp)
+	}
+
+	if _, err := f.exports.GetExportJob(aliceContext(), job.ID); !errors.Is(err, policy.ErrForbidden) {
+		t.Errorf("GetExportJob as another user: got %v, want forbidden", err)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	job.ExpiresAt = &expired
+	f.jobRepo.jobs[job.ID] = job
+	if err := f.exports.RunJobs(context.Background()); err != nil {
+		t.Fatalf("RunJobs: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expired file not removed: %v", err)
+	}
+	if _, err := f.exports.GetExportJob(ctx, job.ID); !errors.Is(err, entity.ErrExportJobNotFound) {
+		t.Errorf("expired job: got %v, want not found", err)
+	}
+}
+
+func TestExportJobFailure(t *testing.T) {
+	f := newExportFixture(t)
+	f.orderRepo.exportErr = errors.New("connection reset")
+	ctx := aliceContext()
+	alice := uint(1)
+
+	job, err := f.exports.CreateExportJob(ctx, adapter.OrderFilter{UserID: &alice}, exporter.FormatCSV)
+	if err != nil {
+		t.Fatalf("CreateExportJob: %v", err)
+	}
+	if err := f.exports.RunJobs(context.Background()); err != nil {
+		t.Fatalf("RunJobs: %v", err)
+	}
+
+	job, err = f.exports.GetExportJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetExportJob: %v", err)
+	}
+	if job.Status != entity.ExportJobFailed || job.Error != "the export failed" || job.FileName != "" {
+		t.Errorf("got job %+v", job)
+	}
+	if files, _ := os.ReadDir(f.dir); len(files) > 0 {
+		t.Errorf("files left after a failed job: %v", files)
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/importer"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxImportFailures bounds the failures kept in an ImportReport; the others
+// are only counted.
+const maxImportFailures = 1000
+
+// ImportFailure is a row of an import file that was not imported.
+type ImportFailure struct {
+	Line int
+	Err  error
+}
+
+// ImportReport summarizes an import. Failures holds the first failed rows
+// in file order. When the import ends with an error, StoppedAt is the line
+// from which on no row was imported: the first row of the batch that could
+// not be written, or the line after the last row read.
+type ImportReport struct {
+	Rows      int
+	Imported  int
+	Failed    int
+	Failures  []ImportFailure
+	StoppedAt int
+}
+
+// Truncated reports whether some failed rows are missing from Failures.
+func (r *ImportReport) Truncated() bool {
+	return r.Failed > len(r.Failures)
+}
+
+func (r *ImportReport) fail(line int, err error) {
+	r.Failed++
+	if len(r.Failures) < maxImportFailures {
+		r.Failures = append(r.Failures, ImportFailure{Line: line, Err: err})
+	}
+}
+
+// ImportService creates users and orders from import files. Rows are read
+// one at a time and written in batches, each in its own transaction, so a
+// failing row or batch does not undo the batches before it.
+type ImportService interface {
+	ImportUsers(ctx context.Context, rows importer.Reader) (ImportReport, error)
+	ImportOrders(ctx context.Context, rows importer.Reader) (ImportReport, error)
+}
+
+type importService struct {
+	userRepo     adapter.UserRepository
+	orderService OrderService
+	cacheManager adapter.CacheManager
+	batchSize    int
+}
+
+// NewImportService returns an ImportService that writes batchSize rows at a
+// time. Orders are created through orderService.
+func NewImportService(
+	userRepo adapter.UserRepository,
+	orderService OrderService,
+	cacheManager adapter.CacheManager,
+	batchSize int,
+) ImportService {
+	return &importService{
+		userRepo:     userRepo,
+		orderService: orderService,
+		cacheManager: cacheManager,
+		batchSize:    batchSize,
+	}
+}
+
+// importRow is a decoded and validated row waiting for its batch.
+type importRow[T any] struct {
+	line int
+	row  T
+}
+
+// ImportUsers creates the users of rows, which hold api.CreateUser records.
+// Emails already registered, or repeated in the file, fail their row.
+func (s *importService) ImportUsers(ctx context.Context, rows importer.Reader) (ImportReport, error) {
+	if err := policy.RequireAdmin(ctx); err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	err := readImport(ctx, rows, s.batchSize, &report, func(batch []importRow[api.CreateUser]) error {
+		return s.importUsers(ctx, batch, &report)
+	})
+	return report, err
+}
+
+func (s *importService) importUsers(ctx context.Context, batch []importRow[api.CreateUser], report *ImportReport) error {
+	emails := make([]string, len(batch))
+	for i, r := range batch {
+		emails[i] = r.row.Email
+	}
+	existing, err := s.userRepo.IDsByEmail(ctx, emails)
+	if err != nil {
+		return err
+	}
+
+	var users []entity.User
+	var lines []int
+	seen := make(map[string]bool, len(batch))
+	for _, r := range batch {
+		email := entity.NormalizeEmail(r.row.Email)
+		if _, ok := existing[email]; ok || seen[email] {
+			report.fail(r.line, entity.ErrEmailTaken)
+			continue
+		}
+		seen[email] = true
+		users = append(users, entity.User{Name: r.row.Name, Email: email})
+		lines = append(lines, r.line)
+	}
+
+	if err := s.userRepo.CreateBatch(ctx, users); err != nil {
+		// Another request registered one of the emails since the check.
+		if errors.Is(err, entity.ErrEmailTaken) {
+			for _, line := range lines {
+				report.fail(line, err)
+			}
+			return nil
+		}
+		return err
+	}
+	report.Imported += len(users)
+
+	var inv cacheInvalidation
+	for _, user := range users {
+		inv.user(user.ID)
+	}
+	inv.flush(ctx, s.cacheManager)
+	return nil
+}
+
+// ImportOrders creates the orders of rows, which hold api.ImportOrder
+// records, through OrderService.BulkCreateOrders.
+func (s *importService) ImportOrders(ctx context.Context, rows importer.Reader) (ImportReport, error) {
+	if err := policy.RequireAdmin(ctx); err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	err := readImport(ctx, rows, s.batchSize, &report, func(batch []importRow[api.ImportOrder]) error {
+		return s.importOrders(ctx, batch, &report)
+	})
+	return report, err
+}
+
+func (s *importService) importOrders(ctx context.Context, batch []importRow[api.ImportOrder], report *ImportReport) error {
+	emails := make([]string, len(batch))
+	for i, r := range batch {
+		emails[i] = r.row.UserEmail
+	}
+	userIDs, err := s.userRepo.IDsByEmail(ctx, emails)
+	if err != nil {
+		return err
+	}
+
+	validate := validator.New()
+	var reqs []api.CreateOrder
+	var lines []int
+	for _, r := range batch {
+		userID, ok := userIDs[entity.NormalizeEmail(r.row.UserEmail)]
+		if !ok {
+			report.fail(r.line, entity.ErrOrderUserNotFound)
+			continue
+		}
+		req := api.CreateOrder{
+			OrderName:  r.row.OrderName,
+			UserID:     userID,
+			Currency:   r.row.Currency,
+			TaxRateBPS: r.row.TaxRateBPS,
+			Items:      r.row.Items,
+		}
+		if err := validate.Struct(req); err != nil {
+			report.fail(r.line, err)
+			continue
+		}
+		reqs = append(reqs, req)
+		lines = append(lines, r.line)
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	outcomes, err := s.orderService.BulkCreateOrders(ctx, reqs, false)
+	if err != nil {
+		return err
+	}
+	for i, outcome := range outcomes {
+		if outcome.Err != nil {
+			report.fail(lines[i], outcome.Err)
+			continue
+		}
+		report.Imported++
+	}
+	return nil
+}
+
+// readImport reads and validates the rows of an import and passes them to
+// write in batches of size. Rows that cannot be decoded or are invalid are
+// recorded in report and skipped. When reading or writing fails, the rows
+// still waiting for their batch are dropped and report.StoppedAt is set.
+func readImport[T any](ctx context.Context, rows importer.Reader, size int, report *ImportReport, write func([]importRow[T]) error) error {
+	validate := validator.New()
+	batch := make([]importRow[T], 0, size)
+	lastLine := 0
+	stop := func(err error) error {
+		if len(batch) > 0 {
+			report.StoppedAt = batch[0].line
+		} else {
+			report.StoppedAt = lastLine + 1
+		}
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stop(err)
+		}
+
+		var row T
+		line, err := rows.Read(&row)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *importer.RowError
+		if errors.As(err, &rowErr) {
+			lastLine = line
+			report.Rows++
+			report.fail(line, invalidImportRow(rowErr.Err))
+			continue
+		}
+		if err != nil {
+			return stop(err)
+		}
+
+		lastLine = line
+		report.Rows++
+		if err := validate.Struct(row); err != nil {
+			report.fail(line, err)
+			continue
+		}
+		batch = append(batch, importRow[T]{line: line, row: row})
+		if len(batch) == size {
+			if err := write(batch); err != nil {
+				return stop(err)
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := write(batch); err != nil {
+		return stop(err)
+	}
+	return nil
+}
+
+// invalidImportRow reports a row that cannot be decoded, keeping the
+// decoder's description of the problem as the message.
+func invalidImportRow(err error) error {
+	wrapped := apperror.Wrap(entity.ErrInvalidImportRow, err)
+	wrapped.Message = err.Error()
+	return wrapped
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/importer"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
+	"github.com/go-playground/validator/v10"
+)
+
+func newImportFixture(t *testing.T) (*cacheFixture, ImportService) {
+	f := newCacheFixture(t)
+	return f, NewImportService(f.userRepo, f.orders, f.cache, 2)
+}
+
+func importReader(t *testing.T, input string, format importer.Format) importer.Reader {
+	t.Helper()
+	r, err := importer.NewReader(strings.NewReader(input), format)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return r
+}
+
+func checkFailures(t *testing.T, report ImportReport, want map[int]error) {
+	t.Helper()
+	if len(report.Failures) != len(want) {
+		t.Fatalf("got %d failures, want %d: %+v", len(report.Failures), len(want), report.Failures)
+	}
+	for _, failure := range report.Failures {
+		wantErr, ok := want[failure.Line]
+		if !ok {
+			t.Errorf("unexpected failure on line %d: %v", failure.Line, failure.Err)
+			continue
+		}
+		if wantErr == nil {
+			var ve validator.ValidationErrors
+			if !errors.As(failure.Err, &ve) {
+				t.Errorf("line %d: got %v, want a validation error", failure.Line, failure.Err)
+			}
+		} else if !errors.Is(failure.Err, wantErr) {
+			t.Errorf("line %d: got %v, want %v", failure.Line, failure.Err, wantErr)
+		}
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	f, imports := newImportFixture(t)
+	ctx := adminContext()
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	usersList := listCacheKey(ctx, f.cache, usersCacheTag, adapter.UserFilter{}, adapter.PageQuery{})
+
+	input := "name,email\n" +
+		"Carol,carol@example.com\n" +
+		"Alice Again,ALICE@example.com\n" +
+		"Dave,dave@example.com\n" +
+		"Dave Again,dave@example.com\n" +
+		"Erin,not-an-email\n" +
+		"Frank,frank@example.com,extra\n" +
+		"Grace,Grace@Example.com\n"
+	report, err := imports.ImportUsers(ctx, importReader(t, input, importer.FormatCSV))
+	if err != nil {
+		t.Fatalf("ImportUsers: %v", err)
+	}
+
+	if report.Rows != 7 || report.Imported != 3 || report.Failed != 4 {
+		t.Fatalf("got rows %d, imported %d, failed %d", report.Rows, report.Imported, report.Failed)
+	}
+	checkFailures(t, report, map[int]error{
+		3: entity.ErrEmailTaken,
+		5: entity.ErrEmailTaken,
+		6: nil,
+		7: entity.ErrInvalidImportRow,
+	})
+	if len(f.userRepo.users) != 5 {
+		t.Errorf("got %d users, want 5", len(f.userRepo.users))
+	}
+	for _, user := range f.userRepo.users {
+		if user.Name == "Grace" && user.Email != "grace@example.com" {
+			t.Errorf("got email %q, want it lower-cased", user.Email)
+		}
+	}
+	if listCacheKey(ctx, f.cache, usersCacheTag, adapter.UserFilter{}, adapter.PageQuery{}) == usersList {
+		t.Errorf("expected the users list key to change")
+	}
+}
+
+func TestImportOrders(t *testing.T) {
+	_, imports := newImportFixture(t)
+
+	input := `{"user_email":"Alice@example.com","order_name":"Logo"}` + "\n" +
+		`{"user_email":"nobody@example.com","order_name":"Poster"}` + "\n" +
+		`{"user_email":"bob@example.com","order_name":"No"}` + "\n" +
+		`{"user_email":"bob@example.com","order_name":"Banner","items":[{"description":"Print","quantity":1,"unit_price":100}]}` + "\n" +
+		`{"user_email":"bob@example.com","order_name":"Flyer","currency":"USD","items":[{"description":"Print","quantity":1,"unit_price":100}]}` + "\n"
+	report, err := imports.ImportOrders(adminContext(), importReader(t, input, importer.FormatNDJSON))
+	if err != nil {
+		t.Fatalf("ImportOrders: %v", err)
+	}
+
+	if report.Rows != 5 || report.Imported != 2 || report.Failed != 3 {
+		t.Fatalf("got rows %d, imported %d, failed %d", report.Rows, report.Imported, report.Failed)
+	}
+	if report.Failures[0].Line != 2 || !errors.Is(report.Failures[0].Err, entity.ErrOrderUserNotFound) {
+		t.Errorf("line 2: got %+v", report.Failures[0])
+	}
+	if report.Failures[1].Line != 3 {
+		t.Errorf("expected line 3 to fail validation, got %+v", report.Failures[1])
+	}
+	if report.Failures[2].Line != 4 || report.Failures[2].Err == nil {
+		t.Errorf("expected line 4 to fail without a currency, got %+v", report.Failures[2])
+	}
+}
+
+func TestImportRequiresAdmin(t *testing.T) {
+	_, imports := newImportFixture(t)
+	ctx := auth.WithCaller(context.Background(), auth.Caller{UserID: 1, Role: entity.RoleUser})
+
+	if _, err := imports.ImportUsers(ctx, importReader(t, "name,email\n", importer.FormatCSV)); !errors.Is(err, policy.ErrForbidden) {
+		t.Errorf("ImportUsers: got %v, want forbidden", err)
+	}
+	if _, err := imports.ImportOrders(ctx, importReader(t, "", importer.FormatNDJSON)); !errors.Is(err, policy.ErrForbidden) {
+		t.Errorf("ImportOrders: got %v, want forbidden", err)
+	}
+}
+
+func TestImportReportKeepsFirstFailures(t *testing.T) {
+	var report ImportReport
+	for line := 1; line <= maxImportFailures+5; line++ {
+		report.fail(line, entity.ErrInvalidImportRow)
+	}
+	if len(report.Failures) != maxImportFailures || !report.Truncated() || report.Failures[0].Line != 1 {
+		t.Fatalf("got %d failures, truncated %v", len(report.Failures), report.Truncated())
+	}
+}
+
+func TestImportReportsWhereItStopped(t *testing.T) {
+	input := "name,email\n" +
+		"Carol,carol@example.com\n" +
+		"Dave,dave@example.com\n" +
+		"Erin,erin@example.com\n" +
+		"Frank,frank@example.com\n"
+	writeErr := errors.New("connection reset")
+
+	var report ImportReport
+	batches := 0
+	err := readImport(context.Background(), importReader(t, input, importer.FormatCSV), 2, &report, func(batch []importRow[api.CreateUser]) error {
+		if batches++; batches == 2 {
+			return writeErr
+		}
+		report.Imported += len(batch)
+		return nil
+	})
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("got %v, want the write error", err)
+	}
+	if report.Imported != 2 || report.StoppedAt != 4 {
+		t.Errorf("got imported %d, stopped at %d; want 2, 4", report.Imported, report.StoppedAt)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	report = ImportReport{}
+	err = readImport(ctx, importReader(t, input, importer.FormatCSV), 2, &report, func(batch []importRow[api.CreateUser]) error {
+		report.Imported += len(batch)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want canceled", err)
+	}
+	if report.Imported != 2 || report.StoppedAt != 4 {
+		t.Errorf("got imported %d, stopped at %d; want 2, 4", report.Imported, report.StoppedAt)
+	}
+}
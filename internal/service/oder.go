@@ -2,23 +2,33 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
 	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
 	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderService interface {
-	GetAllOrders(ctx context.Context) ([]entity.Order, error)
+	GetAllOrders(ctx context.Context, filter adapter.OrderFilter, page adapter.PageQuery) ([]entity.Order, adapter.PageInfo, error)
 	GetOrderByID(ctx context.Context, id uint) (entity.Order, error)
-	CreateOrder(ctx context.Context, orderName string, userID uint) (entity.Order, error)
-	UpdateOrder(ctx context.Context, id uint, orderName string, userID uint) (entity.Order, error)
-	PartialUpdateOrder(ctx context.Context, id uint, orderName *string, userID *uint) (entity.Order, error)
-	DeleteOrder(ctx context.Context, id uint) error
+	CreateOrder(ctx context.Context, req api.CreateOrder) (entity.Order, error)
+	UpdateOrder(ctx context.Context, id uint, ifMatch []uint, req api.CreateOrder) (entity.Order, error)
+	PartialUpdateOrder(ctx context.Context, id uint, ifMatch []uint, req api.PartiallyUpdateOrder) (entity.Order, error)
+	DeleteOrder(ctx context.Context, id uint, ifMatch []uint) error
+	RestoreOrder(ctx context.Context, id uint) (entity.Order, error)
+	TransitionOrder(ctx context.Context, id uint, to entity.OrderStatus, changedBy *uint, reason string) (entity.Order, error)
+	GetOrderStatusHistory(ctx context.Context, id uint) ([]entity.OrderStatusHistory, error)
+
+	BulkCreateOrders(ctx context.Context, reqs []api.CreateOrder, atomic bool) ([]BulkOutcome, error)
+	BulkUpdateOrders(ctx context.Context, reqs []api.BulkUpdateOrder, atomic bool) ([]BulkOutcome, error)
+	BulkDeleteOrders(ctx context.Context, reqs []api.BulkDeleteOrder, atomic bool) ([]BulkOutcome, error)
 
 	CreateUserAndOrder(ctx context.Context, req api.CreateUserAndOrderRequest) error
 }
@@ -27,81 +37,87 @@ type orderService struct {
 	orderRepo    adapter.OrderRepository
 	userRepo     adapter.UserRepository
 	cacheManager adapter.CacheManager
+	lists        *adapter.LoadGroup
 }
 
 func NewOrderService(
 	orderRepo adapter.OrderRepository,
 	userRepo adapter.UserRepository,
 	cacheManager adapter.CacheManager,
+	listRefresh adapter.RefreshPolicy,
 ) OrderService {
 	return &orderService{
 		orderRepo:    orderRepo,
 		userRepo:     userRepo,
 		cacheManager: cacheManager,
+		lists:        adapter.NewLoadGroup(cacheManager, listRefresh),
 	}
 }
 
-func (s *orderService) GetAllOrders(ctx context.Context) ([]entity.Order, error) {
-	cachedData, err := s.cacheManager.Get("orders")
-	if err == nil && cachedData != "" {
-		var orders []entity.Order
-		if err := json.Unmarshal([]byte(cachedData), &orders); err == nil {
-			return orders, err
-		}
+type orderPage struct {
+	Orders   []entity.Order   `json:"orders"`
+	PageInfo adapter.PageInfo `json:"page_info"`
+}
 
-		return orders, nil
+// GetAllOrders lists every order for admins. Other callers must filter by
+// their own user id.
+func (s *orderService) GetAllOrders(ctx context.Context, filter adapter.OrderFilter, page adapter.PageQuery) ([]entity.Order, adapter.PageInfo, error) {
+	if err := authorizeOrderFilter(ctx, filter); err != nil {
+		return nil, adapter.PageInfo{}, err
 	}
 
-	resp, err := s.orderRepo.GetAll(ctx)
-	if err != nil {
-		return []entity.Order{}, err
-	}
+	page = page.Normalize()
+	cacheKey := listCacheKey(ctx, s.cacheManager, ordersCacheTag, filter, page)
 
-	if cacheErr := s.cacheManager.Set("orders", resp); cacheErr != nil {
-		return nil, err
+	cached, err := adapter.Load(ctx, s.lists, cacheKey, func(ctx context.Context) (orderPage, error) {
+		orders, pageInfo, err := s.orderRepo.List(ctx, filter, page)
+		return orderPage{Orders: orders, PageInfo: pageInfo}, err
+	})
+	if err != nil {
+		return []entity.Order{}, adapter.PageInfo{}, err
 	}
 
-	return resp, nil
+	return cached.Orders, cached.PageInfo, nil
 }
 
-func (s *orderService) GetOrderByID(ctx context.Context, id uint) (entity.Order, error) {
-	cacheKey := fmt.Sprintf("order: %d", id)
-	cachedData, err := s.cacheManager.Get(cacheKey)
-	if err == nil && cachedData != "" {
-		var order entity.Order
-		if err := json.Unmarshal([]byte(cachedData), &order); err != nil {
-			return entity.Order{}, err
-		}
-		return order, nil
+// authorizeOrderFilter allows users to read their own orders and admins to
+// read all orders, including the deleted ones.
+func authorizeOrderFilter(ctx context.Context, filter adapter.OrderFilter) error {
+	if filter.UserID == nil || filter.IncludeDeleted {
+		return policy.RequireAdmin(ctx)
 	}
+	return policy.AuthorizeUser(ctx, *filter.UserID)
+}
 
-	resp, err := s.orderRepo.GetByID(ctx, id)
-
+func (s *orderService) GetOrderByID(ctx context.Context, id uint) (entity.Order, error) {
+	order, err := adapter.GetOrLoad(ctx, s.cacheManager, orderCacheKey(id), 0, func(ctx context.Context) (entity.Order, error) {
+		return s.orderRepo.GetByID(ctx, id)
+	})
 	if err != nil {
 		return entity.Order{}, err
 	}
-
-	if err := s.cacheManager.Set(cacheKey, resp); err != nil {
+	if err := policy.AuthorizeOrder(ctx, order); err != nil {
 		return entity.Order{}, err
 	}
 
-	return resp, nil
+	return order, nil
 }
 
-func (s *orderService) CreateOrder(ctx context.Context, orderName string, userID uint) (entity.Order, error) {
-	cacheKey := fmt.Sprintf("order: %d", userID)
-
-	if err := s.cacheManager.Delete("orders"); err != nil {
-		return entity.Order{}, err
-	}
-
-	if err := s.cacheManager.Delete(cacheKey); err != nil {
+func (s *orderService) CreateOrder(ctx context.Context, req api.CreateOrder) (entity.Order, error) {
+	if err := policy.AuthorizeUser(ctx, req.UserID); err != nil {
 		return entity.Order{}, err
 	}
 
 	order := entity.Order{
-		OrderName: orderName,
-		UserID:    userID,
+		OrderName:  req.OrderName,
+		UserID:     req.UserID,
+		Status:     entity.OrderStatusDraft,
+		Currency:   req.Currency,
+		TaxRateBPS: req.TaxRateBPS,
+		Items:      newOrderItems(req.Currency, req.Items),
+	}
+	if err := order.CalculateTotals(); err != nil {
+		return entity.Order{}, err
 	}
 
 	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
@@ -114,104 +130,279 @@ func (s *orderService) CreateOrder(ctx context.Context, orderName string, userID
 		return entity.Order{}, err
 	}
 
+	var inv cacheInvalidation
+	inv.order(order.ID, order.UserID)
+	inv.flush(ctx, s.cacheManager)
+
 	return order, nil
 }
 
-func (s *orderService) UpdateOrder(ctx context.Context, id uint, orderName string, userID uint) (entity.Order, error) {
-	if err := s.cacheManager.Delete("orders"); err != nil {
-		return entity.Order{}, err
-	}
-	cacheKey := fmt.Sprintf("order: %d", userID)
+// UpdateOrder replaces the order's fields and items. When ifMatch is not
+// empty, the order must still be at one of those versions.
+func (s *orderService) UpdateOrder(ctx context.Context, id uint, ifMatch []uint, req api.CreateOrder) (entity.Order, error) {
+	var (
+		order      entity.Order
+		prevUserID uint
+	)
+	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrOrderNotFound)
+		}
+		if err := policy.AuthorizeOrder(ctx, order); err != nil {
+			return err
+		}
+		if err := checkVersion(order.Version, ifMatch); err != nil {
+			return err
+		}
+		prevUserID = order.UserID
+		if err := policy.AuthorizeUser(ctx, req.UserID); err != nil {
+			return err
+		}
+
+		order.OrderName = req.OrderName
+		if _, err := s.userRepo.GetByID(ctx, req.UserID); err != nil {
+			if errors.Is(err, entity.ErrUserNotFound) {
+				return apperror.Wrap(entity.ErrOrderUserNotFound, err)
+			}
+			return err
+		}
+		order.UserID = req.UserID
+		order.Currency = req.Currency
+		order.TaxRateBPS = req.TaxRateBPS
+		order.Items = newOrderItems(req.Currency, req.Items)
+
+		if err := order.CalculateTotals(); err != nil {
+			return err
+		}
 
-	if err := s.cacheManager.Delete(cacheKey); err != nil {
+		order.Version++
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
+			return err
+		}
+		return replaceOrderItems(tx, &order)
+	}); err != nil {
 		return entity.Order{}, err
 	}
 
-	var order entity.Order
+	// A reassigned order leaves the cached detail of its previous owner stale.
+	var inv cacheInvalidation
+	inv.order(id, prevUserID)
+	inv.order(id, order.UserID)
+	inv.flush(ctx, s.cacheManager)
+
+	return order, nil
+}
+
+func (s *orderService) PartialUpdateOrder(ctx context.Context, id uint, ifMatch []uint, req api.PartiallyUpdateOrder) (entity.Order, error) {
+	var (
+		order      entity.Order
+		prevUserID uint
+	)
 	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
-		if err := tx.First(&order, id).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&order, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrOrderNotFound)
+		}
+		if err := policy.AuthorizeOrder(ctx, order); err != nil {
+			return err
+		}
+		if err := checkVersion(order.Version, ifMatch); err != nil {
+			return err
+		}
+		prevUserID = order.UserID
+		if req.OrderName != nil {
+			order.OrderName = *req.OrderName
+		}
+		if req.UserID != nil {
+			if err := policy.AuthorizeUser(ctx, *req.UserID); err != nil {
+				return err
+			}
+			if _, err := s.userRepo.GetByID(ctx, *req.UserID); err != nil {
+				if errors.Is(err, entity.ErrUserNotFound) {
+					return apperror.Wrap(entity.ErrOrderUserNotFound, err)
+				}
+				return err
+			}
+			order.UserID = *req.UserID
+		}
+		if err := setCurrency(&order, req); err != nil {
 			return err
 		}
+		if req.TaxRateBPS != nil {
+			order.TaxRateBPS = *req.TaxRateBPS
+		}
+		if req.Items != nil {
+			order.Items = newOrderItems(order.Currency, *req.Items)
+		}
 
-		order.OrderName = orderName
-		_, err := s.userRepo.GetByID(ctx, userID)
-		if err != nil {
-			return errors.New("user tidak ditemukan")
+		if err := order.CalculateTotals(); err != nil {
+			return err
 		}
-		order.UserID = userID
 
-		if err := tx.Save(&order).Error; err != nil {
+		order.Version++
+		if err := tx.Omit(clause.Associations).Save(&order).Error; err != nil {
 			return err
 		}
+		if req.Items != nil {
+			return replaceOrderItems(tx, &order)
+		}
 		return nil
 	}); err != nil {
 		return entity.Order{}, err
 	}
 
+	var inv cacheInvalidation
+	inv.order(id, prevUserID)
+	inv.order(id, order.UserID)
+	inv.flush(ctx, s.cacheManager)
+
 	return order, nil
 }
 
-func (s *orderService) PartialUpdateOrder(ctx context.Context, id uint, orderName *string, userID *uint) (entity.Order, error) {
-	if err := s.cacheManager.Delete("orders"); err != nil {
-		return entity.Order{}, err
+// setCurrency applies the currency of req to order. The prices of the
+// items are in the order currency, so a different currency is only accepted
+// together with new items or on an order without items.
+func setCurrency(order *entity.Order, req api.PartiallyUpdateOrder) error {
+	if req.Currency == nil {
+		return nil
+	}
+	if *req.Currency != order.Currency && req.Items == nil && len(order.Items) > 0 {
+		return entity.ErrCurrencyChangeNeedsItems
 	}
-	cacheKey := fmt.Sprintf("order: %d", userID)
+	order.Currency = *req.Currency
+	return nil
+}
 
-	if err := s.cacheManager.Delete(cacheKey); err != nil {
-		return entity.Order{}, err
+func (s *orderService) DeleteOrder(ctx context.Context, id uint, ifMatch []uint) error {
+	order, err := s.orderRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := policy.AuthorizeOrder(ctx, order); err != nil {
+		return err
 	}
 
-	var order entity.Order
 	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
-		if err := tx.First(&order, id).Error; err != nil {
+		var locked entity.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, order.ID).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrOrderNotFound)
+		}
+		if err := checkVersion(locked.Version, ifMatch); err != nil {
 			return err
 		}
-		if orderName != nil {
-			order.OrderName = *orderName
+		return tx.Model(&entity.Order{}).Where("id = ?", order.ID).
+			Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")}).Error
+	}); err != nil {
+		return err
+	}
+
+	var inv cacheInvalidation
+	inv.order(order.ID, order.UserID)
+	inv.flush(ctx, s.cacheManager)
+	return nil
+}
+
+func (s *orderService) RestoreOrder(ctx context.Context, id uint) (entity.Order, error) {
+	var order entity.Order
+
+	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		var deleted entity.Order
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&deleted, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrOrderNotFound)
+		}
+		if err := policy.AuthorizeOrder(ctx, deleted); err != nil {
+			return err
 		}
-		if userID != nil {
-			_, err := s.userRepo.GetByID(ctx, *userID)
-			if err != nil {
-				return errors.New("user tidak ditemukan")
+
+		var owner entity.User
+		if err := tx.First(&owner, deleted.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.Wrap(entity.ErrOrderOwnerDeleted, err)
 			}
-			order.UserID = *userID
+			return err
 		}
 
-		if err := tx.Save(&order).Error; err != nil {
+		if err := tx.Unscoped().Model(&entity.Order{}).Where("id = ?", deleted.ID).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
 			return err
 		}
-		return nil
+
+		return tx.Preload("User").Preload("Items").First(&order, id).Error
 	}); err != nil {
 		return entity.Order{}, err
 	}
 
+	var inv cacheInvalidation
+	inv.order(id, order.UserID)
+	inv.flush(ctx, s.cacheManager)
+
 	return order, nil
 }
 
-func (s *orderService) DeleteOrder(ctx context.Context, id uint) error {
-	if err := s.cacheManager.Delete("orders"); err != nil {
-		return err
-	}
-	cacheKey := fmt.Sprintf("order: %d", id)
+func (s *orderService) TransitionOrder(ctx context.Context, id uint, to entity.OrderStatus, changedBy *uint, reason string) (entity.Order, error) {
+	var order entity.Order
 
-	if err := s.cacheManager.Delete(cacheKey); err != nil {
-		return err
+	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&order, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrOrderNotFound)
+		}
+		if err := policy.AuthorizeTransition(ctx, order, to); err != nil {
+			return err
+		}
+
+		from := order.Status
+		if !from.CanTransitionTo(to) {
+			return apperror.Conflict(entity.ErrIllegalTransition.Code,
+				fmt.Sprintf("order cannot move from %s to %s", from, to))
+		}
+
+		if err := tx.Model(&entity.Order{}).Where("id = ?", order.ID).
+			Updates(map[string]interface{}{"status": to, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+		order.Status = to
+		order.Version++
+
+		return tx.Create(&entity.OrderStatusHistory{
+			OrderID:    order.ID,
+			FromStatus: from,
+			ToStatus:   to,
+			ChangedBy:  changedBy,
+			Reason:     reason,
+		}).Error
+	}); err != nil {
+		return entity.Order{}, err
 	}
+
+	var inv cacheInvalidation
+	inv.order(id, order.UserID)
+	inv.flush(ctx, s.cacheManager)
+
+	return order, nil
+}
+
+func (s *orderService) GetOrderStatusHistory(ctx context.Context, id uint) ([]entity.OrderStatusHistory, error) {
 	order, err := s.orderRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	return s.orderRepo.Delete(ctx, &order)
+	if err := policy.AuthorizeOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetStatusHistory(ctx, id)
 }
 
 func (s *orderService) CreateUserAndOrder(ctx context.Context, req api.CreateUserAndOrderRequest) error {
-	if err := s.cacheManager.Delete("orders"); err != nil {
+	if err := policy.RequireAdmin(ctx); err != nil {
 		return err
 	}
 
-	return s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
-		user := entity.User{
+	var (
+		user  entity.User
+		order entity.Order
+	)
+
+	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		user = entity.User{
 			Name:  req.User.Name,
 			Email: req.User.Email,
 		}
@@ -220,14 +411,55 @@ func (s *orderService) CreateUserAndOrder(ctx context.Context, req api.CreateUse
 			return err
 		}
 
-		order := entity.Order{
+		order = entity.Order{
 			OrderName: req.Order.OrderName,
 			UserID:    user.ID,
+			Status:    entity.OrderStatusDraft,
 		}
 
 		if err := tx.Save(&order).Error; err != nil {
 			return err
 		}
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	var inv cacheInvalidation
+	inv.user(user.ID)
+	inv.order(order.ID, user.ID)
+	inv.flush(ctx, s.cacheManager)
+	return nil
+}
+
+func newOrderItems(currency string, reqs []api.OrderItemRequest) []entity.OrderItem {
+	items := make([]entity.OrderItem, 0, len(reqs))
+	for _, req := range reqs {
+		itemCurrency := req.Currency
+		if itemCurrency == "" {
+			itemCurrency = currency
+		}
+		items = append(items, entity.OrderItem{
+			Description: req.Description,
+			Quantity:    req.Quantity,
+			UnitPrice:   req.UnitPrice,
+			Currency:    itemCurrency,
+		})
+	}
+	return items
+}
+
+// replaceOrderItems deletes the stored items of order and inserts order.Items
+// in a single batch.
+func replaceOrderItems(tx *gorm.DB, order *entity.Order) error {
+	if err := tx.Where("order_id = ?", order.ID).Delete(&entity.OrderItem{}).Error; err != nil {
+		return err
+	}
+	if len(order.Items) == 0 {
+		return nil
+	}
+	for i := range order.Items {
+		order.Items[i].OrderID = order.ID
+	}
+	return tx.Create(&order.Items).Error
 }
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+func TestSetCurrency(t *testing.T) {
+	eur := "EUR"
+	usd := "USD"
+	items := []api.OrderItemRequest{{Description: "Print", Quantity: 1, UnitPrice: 100}}
+	withItems := func() entity.Order {
+		return entity.Order{Currency: "USD", Items: []entity.OrderItem{{Quantity: 1, UnitPrice: 100, Currency: "USD"}}}
+	}
+
+	order := withItems()
+	if err := setCurrency(&order, api.PartiallyUpdateOrder{Currency: &eur}); !errors.Is(err, entity.ErrCurrencyChangeNeedsItems) {
+		t.Errorf("currency change keeping the items: got %v, want %v", err, entity.ErrCurrencyChangeNeedsItems)
+	}
+	if order.Currency != "USD" {
+		t.Errorf("rejected change applied: currency %s", order.Currency)
+	}
+
+	for name, tc := range map[string]struct {
+		order entity.Order
+		req   api.PartiallyUpdateOrder
+		want  string
+	}{
+		"same currency":        {withItems(), api.PartiallyUpdateOrder{Currency: &usd}, "USD"},
+		"with new items":       {withItems(), api.PartiallyUpdateOrder{Currency: &eur, Items: &items}, "EUR"},
+		"order without items":  {entity.Order{Currency: "USD"}, api.PartiallyUpdateOrder{Currency: &eur}, "EUR"},
+		"currency not changed": {withItems(), api.PartiallyUpdateOrder{}, "USD"},
+	} {
+		if err := setCurrency(&tc.order, tc.req); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if tc.order.Currency != tc.want {
+			t.Errorf("%s: got currency %s, want %s", name, tc.order.Currency, tc.want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bulkBatchSize is the number of rows per INSERT of a bulk write.
+const bulkBatchSize = 100
+
+// BulkOutcome is the result of one item of a bulk request. Err is nil when
+// the item was applied and entity.ErrBulkRolledBack when it was valid but
+// not applied because another item of an atomic request failed.
+type BulkOutcome struct {
+	Order entity.Order
+	Err   error
+}
+
+// bulkOutcomes tracks the outcomes of a bulk request while it runs.
+type bulkOutcomes []BulkOutcome
+
+func (o bulkOutcomes) failed() bool {
+	for _, outcome := range o {
+		if outcome.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rollBack marks every item that has not failed as rolled back.
+func (o bulkOutcomes) rollBack() {
+	for i := range o {
+		if o[i].Err == nil {
+			o[i].Err = entity.ErrBulkRolledBack
+		}
+	}
+}
+
+// BulkCreateOrders creates the orders of reqs with batched inserts in a
+// single transaction. Items whose caller may not create them, whose user
+// does not exist or whose totals are invalid fail on their own; in the atomic
+// mode any failure leaves every order uncreated.
+func (s *orderService) BulkCreateOrders(ctx context.Context, reqs []api.CreateOrder, atomic bool) ([]BulkOutcome, error) {
+	outcomes := make(bulkOutcomes, len(reqs))
+
+	userIDs := make([]uint, 0, len(reqs))
+	for _, req := range reqs {
+		userIDs = append(userIDs, req.UserID)
+	}
+	existing, err := s.userRepo.ExistingIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, req := range reqs {
+		if err := policy.AuthorizeUser(ctx, req.UserID); err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+		if !existing[req.UserID] {
+			outcomes[i].Err = entity.ErrOrderUserNotFound
+			continue
+		}
+
+		order := entity.Order{
+			OrderName:  req.OrderName,
+			UserID:     req.UserID,
+			Status:     entity.OrderStatusDraft,
+			Currency:   req.Currency,
+			TaxRateBPS: req.TaxRateBPS,
+			Items:      newOrderItems(req.Currency, req.Items),
+		}
+		if err := order.CalculateTotals(); err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+		outcomes[i].Order = order
+	}
+	if atomic && outcomes.failed() {
+		outcomes.rollBack()
+		return outcomes, nil
+	}
+
+	var created []*entity.Order
+	for i := range outcomes {
+		if outcomes[i].Err == nil {
+			created = append(created, &outcomes[i].Order)
+		}
+	}
+	if len(created) == 0 {
+		return outcomes, nil
+	}
+
+	if err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).CreateInBatches(created, bulkBatchSize).Error; err != nil {
+			return err
+		}
+
+		var items []*entity.OrderItem
+		for _, order := range created {
+			for j := range order.Items {
+				order.Items[j].OrderID = order.ID
+				items = append(items, &order.Items[j])
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(items, bulkBatchSize).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	var inv cacheInvalidation
+	for _, order := range created {
+		inv.order(order.ID, order.UserID)
+	}
+	inv.flush(ctx, s.cacheManager)
+	return outcomes, nil
+}
+
+// BulkUpdateOrders applies the partial updates of reqs in a single
+// transaction, locking the orders first. The changed orders are written with
+// one batched upsert.
+func (s *orderService) BulkUpdateOrders(ctx context.Context, reqs []api.BulkUpdateOrder, atomic bool) ([]BulkOutcome, error) {
+	outcomes := make(bulkOutcomes, len(reqs))
+
+	userIDs := make([]uint, 0, len(reqs))
+	for _, req := range reqs {
+		if req.UserID != nil {
+			userIDs = append(userIDs, *req.UserID)
+		}
+	}
+	existing, err := s.userRepo.ExistingIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var inv cacheInvalidation
+	err = s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		orders, err := lockOrders(tx, bulkIDs(reqs, func(req api.BulkUpdateOrder) uint { return req.ID }), true)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool, len(reqs))
+		for i, req := range reqs {
+			if seen[req.ID] {
+				outcomes[i].Err = entity.ErrBulkDuplicateID
+				continue
+			}
+			seen[req.ID] = true
+
+			order, ok := orders[req.ID]
+			if !ok {
+				outcomes[i].Err = entity.ErrOrderNotFound
+				continue
+			}
+			outcomes[i].Err = applyBulkUpdate(ctx, &order, req, existing)
+			outcomes[i].Order = order
+		}
+		if atomic && outcomes.failed() {
+			outcomes.rollBack()
+			return nil
+		}
+
+		var changed []*entity.Order
+		var items []*entity.OrderItem
+		var replaced []uint
+		for i, req := range reqs {
+			if outcomes[i].Err != nil {
+				continue
+			}
+			order := &outcomes[i].Order
+			changed = append(changed, order)
+			inv.order(order.ID, orders[order.ID].UserID)
+			inv.order(order.ID, order.UserID)
+			if req.Items != nil {
+				replaced = append(replaced, order.ID)
+				for j := range order.Items {
+					order.Items[j].OrderID = order.ID
+					items = append(items, &order.Items[j])
+				}
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		if err := tx.Omit(clause.Associations).Save(changed).Error; err != nil {
+			return err
+		}
+		if len(replaced) > 0 {
+			if err := tx.Where("order_id IN ?", replaced).Delete(&entity.OrderItem{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(items) > 0 {
+			return tx.CreateInBatches(items, bulkBatchSize).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inv.flush(ctx, s.cacheManager)
+	return outcomes, nil
+}
+
+// applyBulkUpdate checks and applies req to order.
+func applyBulkUpdate(ctx context.Context, order *entity.Order, req api.BulkUpdateOrder, existingUsers map[uint]bool) error {
+	if err := policy.AuthorizeOrder(ctx, *order); err != nil {
+		return err
+	}
+	if req.Version != nil {
+		if err := checkVersion(order.Version, []uint{*req.Version}); err != nil {
+			return err
+		}
+	}
+
+	if req.OrderName != nil {
+		order.OrderName = *req.OrderName
+	}
+	if req.UserID != nil {
+		if err := policy.AuthorizeUser(ctx, *req.UserID); err != nil {
+			return err
+		}
+		if !existingUsers[*req.UserID] {
+			return entity.ErrOrderUserNotFound
+		}
+		order.UserID = *req.UserID
+	}
+	if err := setCurrency(order, req.PartiallyUpdateOrder); err != nil {
+		return err
+	}
+	if req.TaxRateBPS != nil {
+		order.TaxRateBPS = *req.TaxRateBPS
+	}
+	if req.Items != nil {
+		order.Items = newOrderItems(order.Currency, *req.Items)
+	}
+	if err := order.CalculateTotals(); err != nil {
+		return err
+	}
+	order.Version++
+	return nil
+}
+
+// BulkDeleteOrders soft deletes the orders of reqs with a single UPDATE in
+// one transaction.
+func (s *orderService) BulkDeleteOrders(ctx context.Context, reqs []api.BulkDeleteOrder, atomic bool) ([]BulkOutcome, error) {
+	outcomes := make(bulkOutcomes, len(reqs))
+
+	var inv cacheInvalidation
+	err := s.orderRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		orders, err := lockOrders(tx, bulkIDs(reqs, func(req api.BulkDeleteOrder) uint { return req.ID }), false)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool, len(reqs))
+		for i, req := range reqs {
+			if seen[req.ID] {
+				outcomes[i].Err = entity.ErrBulkDuplicateID
+				continue
+			}
+			seen[req.ID] = true
+
+			order, ok := orders[req.ID]
+			if !ok {
+				outcomes[i].Err = entity.ErrOrderNotFound
+				continue
+			}
+			if err := policy.AuthorizeOrder(ctx, order); err != nil {
+				outcomes[i].Err = err
+				continue
+			}
+			if req.Version != nil {
+				if err := checkVersion(order.Version, []uint{*req.Version}); err != nil {
+					outcomes[i].Err = err
+					continue
+				}
+			}
+			outcomes[i].Order = order
+		}
+		if atomic && outcomes.failed() {
+			outcomes.rollBack()
+			return nil
+		}
+
+		var ids []uint
+		for i := range outcomes {
+			if outcomes[i].Err == nil {
+				order := outcomes[i].Order
+				ids = append(ids, order.ID)
+				inv.order(order.ID, order.UserID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&entity.Order{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inv.flush(ctx, s.cacheManager)
+	return outcomes, nil
+}
+
+// lockOrders loads and locks the orders with ids, keyed by id. The rows are
+// locked in id order, so that two bulk requests sharing orders wait for each
+// other instead of deadlocking.
+func lockOrders(tx *gorm.DB, ids []uint, withItems bool) (map[uint]entity.Order, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id")
+	if withItems {
+		query = query.Preload("Items")
+	}
+	var orders []entity.Order
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]entity.Order, len(orders))
+	for _, order := range orders {
+		byID[order.ID] = order
+	}
+	return byID, nil
+}
+
+func bulkIDs[T any](reqs []T, id func(T) uint) []uint {
+	ids := make([]uint, 0, len(reqs))
+	for _, req := range reqs {
+		ids = append(ids, id(req))
+	}
+	return ids
+}
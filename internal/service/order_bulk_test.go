@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/auth"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/gorm"
+)
+
+func TestBulkCreateOrdersAtomicRollsBackOnFailure(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	outcomes, err := f.orders.BulkCreateOrders(ctx, []api.CreateOrder{
+		{OrderName: "Flyer", UserID: 1},
+		{OrderName: "Poster", UserID: 404},
+	}, true)
+	if err != nil {
+		t.Fatalf("BulkCreateOrders: %v", err)
+	}
+
+	if !errors.Is(outcomes[0].Err, entity.ErrBulkRolledBack) {
+		t.Errorf("item 0: got %v, want rolled back", outcomes[0].Err)
+	}
+	if !errors.Is(outcomes[1].Err, entity.ErrOrderUserNotFound) {
+		t.Errorf("item 1: got %v, want user not found", outcomes[1].Err)
+	}
+	if !cached(f.cache, userCacheKey(1)) {
+		t.Errorf("expected the owner to stay cached when nothing was created")
+	}
+}
+
+func TestBulkCreateOrdersBestEffortCreatesValidItems(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	outcomes, err := f.orders.BulkCreateOrders(ctx, []api.CreateOrder{
+		{OrderName: "Flyer", UserID: 1, Currency: "USD", Items: []api.OrderItemRequest{{Description: "Print", Quantity: 2, UnitPrice: 150}}},
+		{OrderName: "Poster", UserID: 404},
+	}, false)
+	if err != nil {
+		t.Fatalf("BulkCreateOrders: %v", err)
+	}
+
+	if outcomes[0].Err != nil {
+		t.Fatalf("item 0: %v", outcomes[0].Err)
+	}
+	if got := outcomes[0].Order.Totals.Subtotal; got != 300 {
+		t.Errorf("item 0 subtotal: got %d, want 300", got)
+	}
+	if !errors.Is(outcomes[1].Err, entity.ErrOrderUserNotFound) {
+		t.Errorf("item 1: got %v, want user not found", outcomes[1].Err)
+	}
+	if cached(f.cache, userCacheKey(1)) {
+		t.Errorf("expected the owner of the created order to be invalidated")
+	}
+}
+
+func TestBulkCreateOrdersChecksCallerPerItem(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := auth.WithCaller(context.Background(), auth.Caller{UserID: 1, Role: entity.RoleUser})
+
+	outcomes, err := f.orders.BulkCreateOrders(ctx, []api.CreateOrder{
+		{OrderName: "Flyer", UserID: 1},
+		{OrderName: "Banner", UserID: 2},
+	}, false)
+	if err != nil {
+		t.Fatalf("BulkCreateOrders: %v", err)
+	}
+
+	if outcomes[0].Err != nil {
+		t.Errorf("item 0: %v", outcomes[0].Err)
+	}
+	if domainErr, ok := apperror.As(outcomes[1].Err); !ok || domainErr.Kind != apperror.KindForbidden {
+		t.Errorf("item 1: got %v, want forbidden", outcomes[1].Err)
+	}
+}
+
+func TestBulkDeleteOrdersReportsMissingAndDuplicateItems(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	outcomes, err := f.orders.BulkDeleteOrders(ctx, []api.BulkDeleteOrder{{ID: 7}, {ID: 7}}, false)
+	if err != nil {
+		t.Fatalf("BulkDeleteOrders: %v", err)
+	}
+
+	if !errors.Is(outcomes[0].Err, entity.ErrOrderNotFound) {
+		t.Errorf("item 0: got %v, want not found", outcomes[0].Err)
+	}
+	if !errors.Is(outcomes[1].Err, entity.ErrBulkDuplicateID) {
+		t.Errorf("item 1: got %v, want duplicate", outcomes[1].Err)
+	}
+}
+
+func TestLockOrdersLocksInIDOrder(t *testing.T) {
+	db := dryRunDB(t)
+	var sql string
+	err := db.Callback().Query().After("gorm:query").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	if _, err := lockOrders(db, []uint{3, 1, 2}, false); err != nil {
+		t.Fatalf("lockOrders: %v", err)
+	}
+	if !strings.Contains(sql, `ORDER BY id FOR UPDATE`) {
+		t.Errorf("expected the rows to be locked in id order, got %s", sql)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
+)
+
+type PurgeService interface {
+	Purge(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type purgeService struct {
+	userRepo        adapter.UserRepository
+	orderRepo       adapter.OrderRepository
+	idempotencyRepo adapter.IdempotencyRepository
+	refreshRepo     adapter.RefreshTokenRepository
+	retention       time.Duration
+}
+
+// NewPurgeService returns a PurgeService. A zero retention keeps soft deleted
+// rows forever.
+func NewPurgeService(
+	userRepo adapter.UserRepository,
+	orderRepo adapter.OrderRepository,
+	idempotencyRepo adapter.IdempotencyRepository,
+	refreshRepo adapter.RefreshTokenRepository,
+	retention time.Duration,
+) PurgeService {
+	return &purgeService{
+		userRepo:        userRepo,
+		orderRepo:       orderRepo,
+		idempotencyRepo: idempotencyRepo,
+		refreshRepo:     refreshRepo,
+		retention:       retention,
+	}
+}
+
+// Purge removes expired idempotency keys and refresh tokens and permanently removes users and
+// orders soft deleted longer ago than the retention. Orders go first because
+// users still referenced by an order are kept.
+func (s *purgeService) Purge(ctx context.Context) error {
+	keys, err := s.idempotencyRepo.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if keys > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "purged expired idempotency keys", "keys", keys)
+	}
+
+	tokens, err := s.refreshRepo.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if tokens > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "purged expired refresh tokens", "tokens", tokens)
+	}
+
+	if s.retention <= 0 {
+		return nil
+	}
+	deletedBefore := time.Now().Add(-s.retention)
+
+	orders, err := s.orderRepo.Purge(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	users, err := s.userRepo.Purge(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	if orders > 0 || users > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "purged soft deleted rows", "orders", orders, "users", users, "deleted_before", deletedBefore)
+	}
+	return nil
+}
+
+func (s *purgeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Purge(ctx); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "purging expired rows failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
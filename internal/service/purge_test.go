@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+)
+
+// purgeLog records the purges run, in order, with the cutoff each was given.
+type purgeLog struct {
+	calls   []string
+	cutoffs map[string]time.Time
+}
+
+func (l *purgeLog) purge(name string, cutoff time.Time) (int64, error) {
+	l.calls = append(l.calls, name)
+	l.cutoffs[name] = cutoff
+	return 0, nil
+}
+
+type purgeUserRepo struct {
+	adapter.UserRepository
+	log *purgeLog
+}
+
+func (r purgeUserRepo) Purge(_ context.Context, deletedBefore time.Time) (int64, error) {
+	return r.log.purge("users", deletedBefore)
+}
+
+type purgeOrderRepo struct {
+	adapter.OrderRepository
+	log *purgeLog
+}
+
+func (r purgeOrderRepo) Purge(_ context.Context, deletedBefore time.Time) (int64, error) {
+	return r.log.purge("orders", deletedBefore)
+}
+
+type purgeIdempotencyRepo struct {
+	adapter.IdempotencyRepository
+	log *purgeLog
+}
+
+func (r purgeIdempotencyRepo) PurgeExpired(_ context.Context, now time.Time) (int64, error) {
+	return r.log.purge("idempotency_keys", now)
+}
+
+type purgeRefreshTokenRepo struct {
+	adapter.RefreshTokenRepository
+	log *purgeLog
+}
+
+func (r purgeRefreshTokenRepo) PurgeExpired(_ context.Context, now time.Time) (int64, error) {
+	return r.log.purge("refresh_tokens", now)
+}
+
+func runPurge(t *testing.T, retention time.Duration) *purgeLog {
+	t.Helper()
+	log := &purgeLog{cutoffs: make(map[string]time.Time)}
+	purge := NewPurgeService(purgeUserRepo{log: log}, purgeOrderRepo{log: log}, purgeIdempotencyRepo{log: log}, purgeRefreshTokenRepo{log: log}, retention)
+	if err := purge.Purge(context.Background()); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	return log
+}
+
+func TestPurgeRemovesRowsDeletedBeforeRetention(t *testing.T) {
+	retention := 30 * 24 * time.Hour
+	start := time.Now()
+	log := runPurge(t, retention)
+
+	want := []string{"idempotency_keys", "refresh_tokens", "orders", "users"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Fatalf("got purges %v, want %v", log.calls, want)
+	}
+	for _, name := range []string{"orders", "users"} {
+		cutoff := log.cutoffs[name]
+		if cutoff.Before(start.Add(-retention)) || cutoff.After(time.Now().Add(-retention)) {
+			t.Errorf("%s: got cutoff %v, want %v before now", name, cutoff, retention)
+		}
+	}
+	for _, name := range []string{"idempotency_keys", "refresh_tokens"} {
+		if cutoff := log.cutoffs[name]; cutoff.Before(start) {
+			t.Errorf("%s: got cutoff %v, want now", name, cutoff)
+		}
+	}
+}
+
+func TestPurgeWithoutRetentionKeepsDeletedRows(t *testing.T) {
+	log := runPurge(t, 0)
+
+	want := []string{"idempotency_keys", "refresh_tokens"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Fatalf("got purges %v, want only %v", log.calls, want)
+	}
+}
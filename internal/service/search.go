@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
+)
+
+const (
+	SearchScopeUsers  = "users"
+	SearchScopeOrders = "orders"
+
+	defaultSearchLimit = 20
+)
+
+var ErrEmptySearchQuery = apperror.Validation("empty_search_query", "search query must contain at least one letter or digit")
+
+type SearchService interface {
+	Search(ctx context.Context, query, scope string, limit int) ([]entity.SearchHit, error)
+}
+
+type searchService struct {
+	searchRepo adapter.SearchRepository
+}
+
+func NewSearchService(searchRepo adapter.SearchRepository) SearchService {
+	return &searchService{
+		searchRepo: searchRepo,
+	}
+}
+
+// Search searches users and orders for admins. Other callers only search
+// their own orders.
+func (s *searchService) Search(ctx context.Context, query, scope string, limit int) ([]entity.SearchHit, error) {
+	caller, err := policy.Caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ownerID *uint
+	if !caller.IsAdmin() {
+		if scope == SearchScopeUsers {
+			return nil, policy.ErrForbidden
+		}
+		scope = SearchScopeOrders
+		ownerID = &caller.UserID
+	}
+
+	terms := adapter.SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	hits := []entity.SearchHit{}
+
+	if scope == "" || scope == SearchScopeUsers {
+		users, err := s.searchRepo.SearchUsers(ctx, terms, limit)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, users...)
+	}
+
+	if scope == "" || scope == SearchScopeOrders {
+		orders, err := s.searchRepo.SearchOrders(ctx, terms, ownerID, limit)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, orders...)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Rank > hits[j].Rank
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
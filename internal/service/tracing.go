@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
+	"github.com/farisarmap/dot-backend-freelance/internal/api"
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/farisarmap/dot-backend-freelance/internal/service")
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func idAttr(key string, id uint) attribute.KeyValue {
+	return attribute.Int64(key, int64(id))
+}
+
+func bulkAttrs(items int, atomic bool) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int("bulk.items", items),
+		attribute.Bool("bulk.atomic", atomic),
+	}
+}
+
+// TraceOrderService wraps s so that every call runs in its own span.
+func TraceOrderService(s OrderService) OrderService {
+	return &tracedOrderService{s}
+}
+
+type tracedOrderService struct {
+	next OrderService
+}
+
+func (s *tracedOrderService) GetAllOrders(ctx context.Context, filter adapter.OrderFilter, page adapter.PageQuery) (orders []entity.Order, info adapter.PageInfo, err error) {
+	ctx, span := startSpan(ctx, "orderService.GetAllOrders")
+	defer func() { endSpan(span, err) }()
+	return s.next.GetAllOrders(ctx, filter, page)
+}
+
+func (s *tracedOrderService) GetOrderByID(ctx context.Context, id uint) (order entity.Order, err error) {
+	ctx, span := startSpan(ctx, "orderService.GetOrderByID", idAttr("order.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.GetOrderByID(ctx, id)
+}
+
+func (s *tracedOrderService) CreateOrder(ctx context.Context, req api.CreateOrder) (order entity.Order, err error) {
+	ctx, span := startSpan(ctx, "orderService.CreateOrder", idAttr("user.id", req.UserID))
+	defer func() { endSpan(span, err) }()
+	return s.next.CreateOrder(ctx, req)
+}
+
+func (s *tracedOrderService) UpdateOrder(ctx context.Context, id uint, ifMatch []uint, req api.CreateOrder) (order entity.Order, err error) {
+	ctx, span := startSpan(ctx, "orderService.UpdateOrder", idAttr("order.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.UpdateOrder(ctx, id, ifMatch, req)
+}
+
+func (s *tracedOrderService) PartialUpdateOrder(ctx context.Context, id uint, ifMatch []uint, req api.PartiallyUpdateOrder) (order entity.Order, err error) {
+	ctx, span := startSpan(ctx, "orderService.PartialUpdateOrder", idAttr("order.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.PartialUpdateOrder(ctx, id, ifMatch, req)
+}
+
+func (s *tracedOrderService) DeleteOrder(ctx context.Context, id uint, ifMatch []uint) (err error) {
+	ctx, span := startSpan(ctx, "orderService.DeleteOrder", idAttr("order.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.DeleteOrder(ctx, id, ifMatch)
+}
+
+func (s *tracedOrderService) RestoreOrder(ctx context.Context, id uint) (order entity.Order, err error) {
+	ctx, span := startSpan(ctx, "orderService.RestoreOrder", idAttr("order.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.RestoreOrder(ctx, id)
+}
+
+func (s *tracedOrderService) BulkCreateOrders(ctx context.Context, reqs []api.CreateOrder, atomic bool) (outcomes []BulkOutcome, err error) {
+	ctx, span := startSpan(ctx, "orderService.BulkCreateOrders", bulkAttrs(len(reqs), atomic)...)
+	defer func() { endSpan(span, err) }()
+	return s.next.BulkCreateOrders(ctx, reqs, atomic)
+}
+
+func (s *tracedOrderService) BulkUpdateOrders(ctx context.Context, reqs []api.BulkUpdateOrder, atomic bool) (outcomes []BulkOutcome, err error) {
+	ctx, span := startSpan(ctx, "orderService.BulkUpdateOrders", bulkAttrs(len(reqs), atomic)...)
+	defer func() { endSpan(span, err) }()
+	return s.next.BulkUpdateOrders(ctx, reqs, atomic)
+}
+
+func (s *tracedOrderService) BulkDeleteOrders(ctx context.Context, reqs []api.BulkDeleteOrder, atomic bool) (outcomes []BulkOutcome, err error) {
+	ctx, span := startSpan(ctx, "orderService.BulkDeleteOrders", bulkAttrs(len(reqs), atomic)...)
+	defer func() { endSpan(span, err) }()
+	return s.next.BulkDeleteOrders(ctx, reqs, atomic)
+}
+
+func (s *tracedOrderService) TransitionOrder(ctx context.Context, id uint, to entity.OrderStatus, changedBy *uint, reason string) (order entity.Order, err error) {
+	ctx, span := startSpan(ctx, "orderService.TransitionOrder",
+		idAttr("order.id", id),
+		attribute.String("order.status", string(to)),
+	)
+	defer func() { endSpan(span, err) }()
+	return s.next.TransitionOrder(ctx, id, to, changedBy, reason)
+}
+
+func (s *tracedOrderService) GetOrderStatusHistory(ctx context.Context, id uint) (history []entity.OrderStatusHistory, err error) {
+	ctx, span := startSpan(ctx, "orderService.GetOrderStatusHistory", idAttr("order.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.GetOrderStatusHistory(ctx, id)
+}
+
+func (s *tracedOrderService) CreateUserAndOrder(ctx context.Context, req api.CreateUserAndOrderRequest) (err error) {
+	ctx, span := startSpan(ctx, "orderService.CreateUserAndOrder")
+	defer func() { endSpan(span, err) }()
+	return s.next.CreateUserAndOrder(ctx, req)
+}
+
+// TraceUserService wraps s so that every call runs in its own span.
+func TraceUserService(s UserService) UserService {
+	return &tracedUserService{s}
+}
+
+type tracedUserService struct {
+	next UserService
+}
+
+func (s *tracedUserService) GetAllUsers(ctx context.Context, filter adapter.UserFilter, page adapter.PageQuery) (users []entity.User, info adapter.PageInfo, err error) {
+	ctx, span := startSpan(ctx, "userService.GetAllUsers")
+	defer func() { endSpan(span, err) }()
+	return s.next.GetAllUsers(ctx, filter, page)
+}
+
+func (s *tracedUserService) GetUserByID(ctx context.Context, id uint) (user entity.User, err error) {
+	ctx, span := startSpan(ctx, "userService.GetUserByID", idAttr("user.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *tracedUserService) CreateUser(ctx context.Context, name, email string) (user entity.User, err error) {
+	ctx, span := startSpan(ctx, "userService.CreateUser")
+	defer func() { endSpan(span, err) }()
+	return s.next.CreateUser(ctx, name, email)
+}
+
+func (s *tracedUserService) UpdateUser(ctx context.Context, id uint, ifMatch []uint, name, email string) (user entity.User, err error) {
+	ctx, span := startSpan(ctx, "userService.UpdateUser", idAttr("user.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.UpdateUser(ctx, id, ifMatch, name, email)
+}
+
+func (s *tracedUserService) PartialUpdateUser(ctx context.Context, id uint, ifMatch []uint, name, email *string) (user entity.User, err error) {
+	ctx, span := startSpan(ctx, "userService.PartialUpdateUser", idAttr("user.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.PartialUpdateUser(ctx, id, ifMatch, name, email)
+}
+
+func (s *tracedUserService) DeleteUser(ctx context.Context, id uint, ifMatch []uint) (err error) {
+	ctx, span := startSpan(ctx, "userService.DeleteUser", idAttr("user.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.DeleteUser(ctx, id, ifMatch)
+}
+
+func (s *tracedUserService) RestoreUser(ctx context.Context, id uint) (user entity.User, err error) {
+	ctx, span := startSpan(ctx, "userService.RestoreUser", idAttr("user.id", id))
+	defer func() { endSpan(span, err) }()
+	return s.next.RestoreUser(ctx, id)
+}
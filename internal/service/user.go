@@ -2,96 +2,96 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"time"
 
 	"github.com/farisarmap/dot-backend-freelance/internal/adapter"
 	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"github.com/farisarmap/dot-backend-freelance/internal/policy"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserService interface {
-	GetAllUsers(ctx context.Context) ([]entity.User, error)
+	GetAllUsers(ctx context.Context, filter adapter.UserFilter, page adapter.PageQuery) ([]entity.User, adapter.PageInfo, error)
 	GetUserByID(ctx context.Context, id uint) (entity.User, error)
 	CreateUser(ctx context.Context, name, email string) (entity.User, error)
-	UpdateUser(ctx context.Context, id uint, name, email string) (entity.User, error)
-	PartialUpdateUser(ctx context.Context, id uint, name, email *string) (entity.User, error)
-	DeleteUser(ctx context.Context, id uint) error
+	UpdateUser(ctx context.Context, id uint, ifMatch []uint, name, email string) (entity.User, error)
+	PartialUpdateUser(ctx context.Context, id uint, ifMatch []uint, name, email *string) (entity.User, error)
+	DeleteUser(ctx context.Context, id uint, ifMatch []uint) error
+	RestoreUser(ctx context.Context, id uint) (entity.User, error)
 }
 
 type userService struct {
 	userRepo     adapter.UserRepository
 	orderRepo    adapter.OrderRepository
 	cacheManager adapter.CacheManager
+	lists        *adapter.LoadGroup
 }
 
 func NewUserService(
 	userRepo adapter.UserRepository,
 	orderRepo adapter.OrderRepository,
 	cacheManager adapter.CacheManager,
+	listRefresh adapter.RefreshPolicy,
 ) UserService {
 	return &userService{
 		userRepo:     userRepo,
 		orderRepo:    orderRepo,
 		cacheManager: cacheManager,
+		lists:        adapter.NewLoadGroup(cacheManager, listRefresh),
 	}
 }
 
-func (s *userService) GetAllUsers(ctx context.Context) ([]entity.User, error) {
-	cachedData, err := s.cacheManager.Get("users")
-	if err == nil && cachedData != "" {
-		var users []entity.User
-		if err := json.Unmarshal([]byte(cachedData), &users); err == nil {
-			return users, err
-		}
+type userPage struct {
+	Users    []entity.User    `json:"users"`
+	PageInfo adapter.PageInfo `json:"page_info"`
+}
 
-		return users, nil
+func (s *userService) GetAllUsers(ctx context.Context, filter adapter.UserFilter, page adapter.PageQuery) ([]entity.User, adapter.PageInfo, error) {
+	if err := policy.RequireAdmin(ctx); err != nil {
+		return nil, adapter.PageInfo{}, err
 	}
 
-	resp, err := s.userRepo.GetAll(ctx)
-	if err != nil {
-		return []entity.User{}, err
-	}
-	for i := range resp {
-		for j := range resp[i].Orders {
-			resp[i].Orders[j].User = entity.User{}
+	page = page.Normalize()
+	cacheKey := listCacheKey(ctx, s.cacheManager, usersCacheTag, filter, page)
+
+	cached, err := adapter.Load(ctx, s.lists, cacheKey, func(ctx context.Context) (userPage, error) {
+		users, pageInfo, err := s.userRepo.List(ctx, filter, page)
+		if err != nil {
+			return userPage{}, err
 		}
+		for i := range users {
+			for j := range users[i].Orders {
+				users[i].Orders[j].User = entity.User{}
+			}
+		}
+		return userPage{Users: users, PageInfo: pageInfo}, nil
+	})
+	if err != nil {
+		return []entity.User{}, adapter.PageInfo{}, err
 	}
 
-	s.cacheManager.Set("users", resp)
-	return resp, nil
+	return cached.Users, cached.PageInfo, nil
 }
 
 func (s *userService) GetUserByID(ctx context.Context, id uint) (entity.User, error) {
-	cachedData, err := s.cacheManager.Get("users_detail")
-	if err == nil && cachedData != "" {
-		var users entity.User
-		if err := json.Unmarshal([]byte(cachedData), &users); err == nil {
-			return users, err
-		}
-
-		return users, nil
-	}
-
-	resp, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
+	if err := policy.AuthorizeUser(ctx, id); err != nil {
 		return entity.User{}, err
 	}
 
-	if err := s.cacheManager.Set("users_detail", resp); err != nil {
-		return entity.User{}, err
-	}
-
-	return resp, nil
+	return adapter.GetOrLoad(ctx, s.cacheManager, userCacheKey(id), 0, func(ctx context.Context) (entity.User, error) {
+		return s.userRepo.GetByID(ctx, id)
+	})
 }
 
 func (s *userService) CreateUser(ctx context.Context, name, email string) (entity.User, error) {
-	if err := s.cacheManager.Delete("users"); err != nil {
+	if err := policy.RequireAdmin(ctx); err != nil {
 		return entity.User{}, err
 	}
 
 	user := entity.User{
 		Name:  name,
-		Email: email,
+		Email: entity.NormalizeEmail(email),
 	}
 
 	err := s.userRepo.Transaction(ctx, func(tx *gorm.DB) error {
@@ -104,20 +104,34 @@ func (s *userService) CreateUser(ctx context.Context, name, email string) (entit
 	if err != nil {
 		return entity.User{}, err
 	}
+
+	var inv cacheInvalidation
+	inv.user(user.ID)
+	inv.flush(ctx, s.cacheManager)
 	return user, nil
 }
 
-func (s *userService) UpdateUser(ctx context.Context, id uint, name, email string) (entity.User, error) {
+// UpdateUser replaces the user's fields. When ifMatch is not empty, the user
+// must still be at one of those versions.
+func (s *userService) UpdateUser(ctx context.Context, id uint, ifMatch []uint, name, email string) (entity.User, error) {
+	if err := policy.AuthorizeUser(ctx, id); err != nil {
+		return entity.User{}, err
+	}
+
 	var user entity.User
 
 	err := s.userRepo.Transaction(ctx, func(tx *gorm.DB) error {
-		if err := tx.First(&user, id).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrUserNotFound)
+		}
+		if err := checkVersion(user.Version, ifMatch); err != nil {
 			return err
 		}
 
 		user.Name = name
-		user.Email = email
+		user.Email = entity.NormalizeEmail(email)
 
+		user.Version++
 		if err := tx.Save(&user).Error; err != nil {
 			return err
 		}
@@ -125,22 +139,29 @@ func (s *userService) UpdateUser(ctx context.Context, id uint, name, email strin
 		return nil
 	})
 
-	if err := s.cacheManager.Delete("users"); err != nil {
+	if err != nil {
 		return entity.User{}, err
 	}
 
-	if err := s.cacheManager.Delete("users_detail"); err != nil {
+	if err := s.invalidateUser(ctx, id); err != nil {
 		return entity.User{}, err
 	}
 
-	return user, err
+	return user, nil
 }
 
-func (s *userService) PartialUpdateUser(ctx context.Context, id uint, name, email *string) (entity.User, error) {
+func (s *userService) PartialUpdateUser(ctx context.Context, id uint, ifMatch []uint, name, email *string) (entity.User, error) {
+	if err := policy.AuthorizeUser(ctx, id); err != nil {
+		return entity.User{}, err
+	}
+
 	var user entity.User
 
 	err := s.userRepo.Transaction(ctx, func(tx *gorm.DB) error {
-		if err := tx.First(&user, id).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrUserNotFound)
+		}
+		if err := checkVersion(user.Version, ifMatch); err != nil {
 			return err
 		}
 
@@ -148,9 +169,10 @@ func (s *userService) PartialUpdateUser(ctx context.Context, id uint, name, emai
 			user.Name = *name
 		}
 		if email != nil {
-			user.Email = *email
+			user.Email = entity.NormalizeEmail(*email)
 		}
 
+		user.Version++
 		if err := tx.Save(&user).Error; err != nil {
 			return err
 		}
@@ -158,30 +180,100 @@ func (s *userService) PartialUpdateUser(ctx context.Context, id uint, name, emai
 		return nil
 	})
 
-	if err := s.cacheManager.Delete("users"); err != nil {
+	if err != nil {
 		return entity.User{}, err
 	}
 
-	if err := s.cacheManager.Delete("users_detail"); err != nil {
+	if err := s.invalidateUser(ctx, id); err != nil {
 		return entity.User{}, err
 	}
 
-	return user, err
+	return user, nil
 }
 
-func (s *userService) DeleteUser(ctx context.Context, id uint) error {
+func (s *userService) DeleteUser(ctx context.Context, id uint, ifMatch []uint) error {
+	if err := policy.AuthorizeUser(ctx, id); err != nil {
+		return err
+	}
+
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	if err := s.cacheManager.Delete("users"); err != nil {
+	// The user's orders are soft deleted with the same timestamp so that
+	// RestoreUser can bring back exactly the orders removed along with it.
+	deletedAt := time.Now()
+	if err := s.userRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		var locked entity.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, user.ID).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrUserNotFound)
+		}
+		if err := checkVersion(locked.Version, ifMatch); err != nil {
+			return err
+		}
+
+		deleted := map[string]interface{}{"deleted_at": deletedAt, "version": gorm.Expr("version + 1")}
+		if err := tx.Model(&entity.Order{}).Where("user_id = ?", user.ID).Updates(deleted).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.User{}).Where("id = ?", user.ID).Updates(deleted).Error
+	}); err != nil {
 		return err
 	}
 
-	if err := s.cacheManager.Delete("users_detail"); err != nil {
+	return s.invalidateUser(ctx, user.ID)
+}
+
+func (s *userService) RestoreUser(ctx context.Context, id uint) (entity.User, error) {
+	if err := policy.RequireAdmin(ctx); err != nil {
+		return entity.User{}, err
+	}
+
+	var user entity.User
+
+	err := s.userRepo.Transaction(ctx, func(tx *gorm.DB) error {
+		var deleted entity.User
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&deleted, id).Error; err != nil {
+			return adapter.NotFound(err, entity.ErrUserNotFound)
+		}
+
+		restored := map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}
+		if err := tx.Unscoped().Model(&entity.Order{}).
+			Where("user_id = ? AND deleted_at = ?", deleted.ID, deleted.DeletedAt.Time).
+			Updates(restored).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&entity.User{}).Where("id = ?", deleted.ID).Updates(restored).Error; err != nil {
+			return err
+		}
+
+		return tx.Preload("Orders.Items").First(&user, id).Error
+	})
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if err := s.invalidateUser(ctx, id); err != nil {
+		return entity.User{}, err
+	}
+
+	return user, nil
+}
+
+// invalidateUser drops the cached user and the cached orders embedding it.
+func (s *userService) invalidateUser(ctx context.Context, id uint) error {
+	orderIDs, err := s.orderRepo.IDsByUser(ctx, id)
+	if err != nil {
 		return err
 	}
 
-	return s.userRepo.Delete(ctx, &user)
+	var inv cacheInvalidation
+	inv.user(id)
+	for _, orderID := range orderIDs {
+		inv.order(orderID, id)
+	}
+	inv.flush(ctx, s.cacheManager)
+	return nil
 }
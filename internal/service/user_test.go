@@ -0,0 +1,121 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"gorm.io/gorm"
+)
+
+type statement struct {
+	sql  string
+	vars []interface{}
+}
+
+// captureUpdates records the UPDATE statements built on db.
+func captureUpdates(t *testing.T, db *gorm.DB) *[]statement {
+	t.Helper()
+	var updates []statement
+	err := db.Callback().Update().After("gorm:update").Register("test:capture_updates", func(tx *gorm.DB) {
+		updates = append(updates, statement{sql: tx.Statement.SQL.String(), vars: tx.Statement.Vars})
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	return &updates
+}
+
+// updateOf returns the UPDATE of table, failing the test unless there is
+// exactly one.
+func updateOf(t *testing.T, updates []statement, table string) statement {
+	t.Helper()
+	var found []statement
+	for _, u := range updates {
+		if strings.HasPrefix(u.sql, `UPDATE "`+table+`"`) {
+			found = append(found, u)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("got %d updates of %s, want 1: %v", len(found), table, updates)
+	}
+	return found[0]
+}
+
+func TestDeleteUserSoftDeletesOrdersAtTheSameTime(t *testing.T) {
+	f := newCacheFixture(t)
+	updates := captureUpdates(t, f.userRepo.db)
+
+	if err := f.users.DeleteUser(adminContext(), 1, nil); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	orders := updateOf(t, *updates, "orders")
+	users := updateOf(t, *updates, "users")
+	if !strings.Contains(orders.sql, `"deleted_at"=$1`) || !strings.Contains(orders.sql, "user_id = $3") {
+		t.Fatalf("expected the user's orders to be soft deleted, got %s", orders.sql)
+	}
+	if !strings.Contains(users.sql, `"deleted_at"=$1`) || !strings.Contains(users.sql, "id = $3") {
+		t.Fatalf("expected the user to be soft deleted, got %s", users.sql)
+	}
+	if orders.vars[0] != users.vars[0] || orders.vars[2] != uint(1) || users.vars[2] != uint(1) {
+		t.Errorf("expected user 1 and its orders to share deleted_at, got %v and %v", orders.vars, users.vars)
+	}
+}
+
+func TestRestoreUserRestoresOnlyOrdersDeletedWithIt(t *testing.T) {
+	f := newCacheFixture(t)
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// The dry run database finds nothing, so the deleted user is filled in
+	// once it has been looked up.
+	err := f.userRepo.db.Callback().Query().After("gorm:query").Register("test:deleted_user", func(tx *gorm.DB) {
+		if user, ok := tx.Statement.Dest.(*entity.User); ok && tx.Statement.Unscoped {
+			*user = entity.User{ID: 1, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	updates := captureUpdates(t, f.userRepo.db)
+
+	if _, err := f.users.RestoreUser(adminContext(), 1); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	orders := updateOf(t, *updates, "orders")
+	if !strings.Contains(orders.sql, "user_id = $3 AND deleted_at = $4") {
+		t.Fatalf("expected only the orders deleted with the user to be restored, got %s", orders.sql)
+	}
+	if orders.vars[0] != nil || orders.vars[2] != uint(1) || orders.vars[3] != deletedAt {
+		t.Errorf("got values %v, want deleted_at cleared for user 1 at %v", orders.vars, deletedAt)
+	}
+	if users := updateOf(t, *updates, "users"); users.vars[0] != nil {
+		t.Errorf("expected the user's deleted_at to be cleared, got %v", users.vars)
+	}
+}
+
+func TestUserEmailsAreStoredLowerCased(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+	email := "Alice.Smith@Example.com"
+
+	created, err := f.users.CreateUser(ctx, "Alice", email)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	updated, err := f.users.UpdateUser(ctx, 1, nil, "Alice", email)
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	patched, err := f.users.PartialUpdateUser(ctx, 1, nil, nil, &email)
+	if err != nil {
+		t.Fatalf("PartialUpdateUser: %v", err)
+	}
+
+	for _, user := range []entity.User{created, updated, patched} {
+		if user.Email != "alice.smith@example.com" {
+			t.Errorf("got email %q, want it lower-cased", user.Email)
+		}
+	}
+}
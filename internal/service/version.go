@@ -0,0 +1,18 @@
+package service
+
+import "github.com/farisarmap/dot-backend-freelance/internal/entity"
+
+// checkVersion rejects a write to a row at version unless it is one of the
+// versions the caller based the write on. An empty ifMatch accepts any
+// version.
+func checkVersion(version uint, ifMatch []uint) error {
+	if len(ifMatch) == 0 {
+		return nil
+	}
+	for _, v := range ifMatch {
+		if v == version {
+			return nil
+		}
+	}
+	return entity.ErrVersionMismatch
+}
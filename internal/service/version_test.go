@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+)
+
+func TestCheckVersion(t *testing.T) {
+	if err := checkVersion(3, nil); err != nil {
+		t.Fatalf("expected no If-Match to accept any version, got %v", err)
+	}
+	if err := checkVersion(3, []uint{2, 3}); err != nil {
+		t.Fatalf("expected a listed version to match, got %v", err)
+	}
+	if err := checkVersion(3, []uint{2}); !errors.Is(err, entity.ErrVersionMismatch) {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+}
+
+func TestStaleUpdateKeepsCache(t *testing.T) {
+	f := newCacheFixture(t)
+	ctx := adminContext()
+
+	if _, err := f.users.GetUserByID(ctx, 1); err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	// The dry run database reads every row at version 0.
+	if _, err := f.users.UpdateUser(ctx, 1, []uint{1}, "Alice Smith", "alice@example.com"); !errors.Is(err, entity.ErrVersionMismatch) {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+	if !cached(f.cache, userCacheKey(1)) {
+		t.Fatalf("expected a rejected update to keep the cache")
+	}
+	if err := f.orders.DeleteOrder(ctx, 20, []uint{1}); !errors.Is(err, entity.ErrVersionMismatch) {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+}
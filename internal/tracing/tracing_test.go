@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/entity"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormPluginStartsChildSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.Use(NewGormPlugin()); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	var orders []entity.Order
+	db.WithContext(ctx).Where("status = ?", "draft").Find(&orders)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a query span and the parent, got %d spans", len(spans))
+	}
+	query := spans[0]
+	if query.Name() != "gorm.query" {
+		t.Fatalf("unexpected span %q", query.Name())
+	}
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("expected the query span to be a child of the request span")
+	}
+	for _, attr := range query.Attributes() {
+		if attr.Key == "db.query.text" && !strings.Contains(attr.Value.AsString(), `"orders"`) {
+			t.Fatalf("unexpected statement %q", attr.Value.AsString())
+		}
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), Options{ServiceName: "test", Exporter: ExporterFile, FilePath: path})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(data), `"Name":"work"`) {
+		t.Fatalf("expected the span in the file, got %s", data)
+	}
+
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Fatal("expected an unknown exporter to be rejected")
+	}
+}
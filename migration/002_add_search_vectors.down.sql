@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_search_vector;
+ALTER TABLE orders DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS idx_users_search_vector;
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,14 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(email, '') || ' ' || regexp_replace(coalesce(email, ''), '[@._+-]+', ' ', 'g')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(order_name, '')), 'A')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_orders_search_vector ON orders USING GIN (search_vector);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    filter JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    rows BIGINT NOT NULL DEFAULT 0,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- Workers look for jobs to run; finished jobs are looked up by expiry.
+CREATE INDEX IF NOT EXISTS idx_export_jobs_status ON export_jobs (status, id);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at ON export_jobs (expires_at);
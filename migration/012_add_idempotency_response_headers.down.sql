@@ -0,0 +1,3 @@
+ALTER TABLE idempotency_keys
+    DROP COLUMN IF EXISTS etag,
+    DROP COLUMN IF EXISTS location;
@@ -0,0 +1,3 @@
+ALTER TABLE idempotency_keys
+    ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS etag VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Emails stay lower-cased; only the index is restored.
+DROP INDEX IF EXISTS idx_users_email_active;
+CREATE UNIQUE INDEX idx_users_email_active ON users (email) WHERE deleted_at IS NULL;
@@ -0,0 +1,10 @@
+-- Emails are stored lower-cased and unique whatever their case. Active users
+-- whose emails differ only in case make the index fail; find them with
+--   SELECT LOWER(email), array_agg(id) FROM users
+--   WHERE deleted_at IS NULL GROUP BY 1 HAVING count(*) > 1;
+-- and change or delete all but one before migrating.
+DROP INDEX IF EXISTS idx_users_email_active;
+
+UPDATE users SET email = LOWER(email) WHERE email <> LOWER(email);
+
+CREATE UNIQUE INDEX idx_users_email_active ON users (LOWER(email)) WHERE deleted_at IS NULL;
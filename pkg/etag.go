@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// EntityTag returns the ETag of a versioned resource whose representation is
+// body. The version part is what If-Match is checked against; the hash part
+// changes with the representation, including the records it embeds, so that
+// If-None-Match never serves a stale copy.
+func EntityTag(version uint, body interface{}) string {
+	return fmt.Sprintf(`"%d-%s"`, version, representationHash(body))
+}
+
+// ListTag returns the ETag of a list response.
+func ListTag(body interface{}) string {
+	return `"` + representationHash(body) + `"`
+}
+
+func representationHash(body interface{}) string {
+	data, _ := json.Marshal(body)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// IfMatchVersions returns the resource versions listed in an If-Match header.
+// It returns nil when the header is missing or "*", meaning any version is
+// accepted. Tags that are weak or were not issued by EntityTag yield version
+// 0, which no resource has, so that they fail the precondition.
+func IfMatchVersions(header string) []uint {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	var versions []uint
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		version, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+		v, err := strconv.ParseUint(version, 10, 0)
+		if err != nil || strings.HasPrefix(tag, "W/") {
+			v = 0
+		}
+		versions = append(versions, uint(v))
+	}
+	return versions
+}
+
+// NoneMatch reports whether tag fails an If-None-Match header, i.e. the
+// client's copy is out of date. Tags are compared weakly.
+func NoneMatch(header, tag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return true
+	}
+	if header == "*" {
+		return false
+	}
+	for _, t := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == strings.TrimPrefix(tag, "W/") {
+			return false
+		}
+	}
+	return true
+}
+
+// JSONWithETag writes body as JSON with tag as its ETag, or 304 Not Modified
+// when the request's If-None-Match header already holds tag.
+func JSONWithETag(c echo.Context, status int, tag string, body interface{}) error {
+	c.Response().Header().Set(HeaderETag, tag)
+	if !NoneMatch(c.Request().Header.Get(HeaderIfNoneMatch), tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(status, body)
+}
@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestEntityTag(t *testing.T) {
+	a := EntityTag(3, map[string]string{"name": "Alice"})
+	if a != EntityTag(3, map[string]string{"name": "Alice"}) {
+		t.Fatal("expected the same representation to get the same tag")
+	}
+	if a == EntityTag(3, map[string]string{"name": "Bob"}) {
+		t.Fatal("expected a different representation to get a different tag")
+	}
+	if got := IfMatchVersions(a); !reflect.DeepEqual(got, []uint{3}) {
+		t.Fatalf("expected version 3 from %s, got %v", a, got)
+	}
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   []uint
+	}{
+		{"", nil},
+		{"*", nil},
+		{`"4-abc"`, []uint{4}},
+		{`"4-abc", "5-def"`, []uint{4, 5}},
+		{`W/"4-abc"`, []uint{0}},
+		{`"garbage"`, []uint{0}},
+	}
+	for _, tc := range testCases {
+		if got := IfMatchVersions(tc.header); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("IfMatchVersions(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestJSONWithETag(t *testing.T) {
+	e := echo.New()
+	body := ResponseSuccess("Success", map[string]int{"id": 1})
+	tag := EntityTag(1, body.Data)
+
+	testCases := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"no header", "", http.StatusOK},
+		{"current tag", tag, http.StatusNotModified},
+		{"weak current tag", "W/" + tag, http.StatusNotModified},
+		{"one of several", `"0-old", ` + tag, http.StatusNotModified},
+		{"stale tag", `"0-old"`, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set(HeaderIfNoneMatch, tc.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			if err := JSONWithETag(e.NewContext(req, rec), http.StatusOK, tag, body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tc.wantStatus || rec.Header().Get(HeaderETag) != tag {
+				t.Fatalf("got %d with ETag %q", rec.Code, rec.Header().Get(HeaderETag))
+			}
+		})
+	}
+}
@@ -1,16 +1,28 @@
 package pkg
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/farisarmap/dot-backend-freelance/internal/logging"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
 type Response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Status     string      `json:"status"`
+	Message    string      `json:"message"`
+	Code       string      `json:"code,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
 }
 
 func ResponseSuccess(message string, data interface{}) Response {
@@ -21,6 +33,12 @@ func ResponseSuccess(message string, data interface{}) Response {
 	}
 }
 
+func ResponseSuccessWithPagination(message string, data interface{}, pagination Pagination) Response {
+	resp := ResponseSuccess(message, data)
+	resp.Pagination = &pagination
+	return resp
+}
+
 func ResponseError(message string, data interface{}) Response {
 	return Response{
 		Status:  "error",
@@ -29,15 +47,108 @@ func ResponseError(message string, data interface{}) Response {
 	}
 }
 
-func HandleError(c echo.Context, err error, code int) error {
-	if ve, ok := err.(validator.ValidationErrors); ok {
+var kindStatus = map[apperror.Kind]int{
+	apperror.KindBadRequest:           http.StatusBadRequest,
+	apperror.KindValidation:           http.StatusUnprocessableEntity,
+	apperror.KindNotFound:             http.StatusNotFound,
+	apperror.KindConflict:             http.StatusConflict,
+	apperror.KindUnauthorized:         http.StatusUnauthorized,
+	apperror.KindForbidden:            http.StatusForbidden,
+	apperror.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	apperror.KindPreconditionRequired: http.StatusPreconditionRequired,
+}
+
+var statusCode = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "validation_failed",
+	http.StatusPreconditionRequired:  "precondition_required",
+	http.StatusTooManyRequests:       "too_many_requests",
+	http.StatusInternalServerError:   "internal_error",
+	http.StatusServiceUnavailable:    "service_unavailable",
+}
+
+// ErrorResponse maps err to its HTTP status and response body. Domain errors
+// and echo.HTTPError carry their own status; other errors use fallbackStatus.
+// Field validation failures get 422 like domain validation errors, so a
+// request is rejected the same way whichever layer catches the mistake.
+func ErrorResponse(err error, fallbackStatus int) (int, Response) {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
 		var fieldErrors []string
 		for _, fe := range ve {
 			fieldErrors = append(fieldErrors, fe.Field()+" failed on the '"+fe.Tag()+"' tag")
 		}
 		resp := ResponseError("Validation error", fieldErrors)
-		return c.JSON(http.StatusBadRequest, resp)
+		resp.Code = statusCode[http.StatusUnprocessableEntity]
+		return http.StatusUnprocessableEntity, resp
+	}
+
+	if domainErr, ok := apperror.As(err); ok {
+		status, ok := kindStatus[domainErr.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		resp := ResponseError(domainErr.Message, nil)
+		resp.Code = domainErr.Code
+		return status, resp
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		message, ok := he.Message.(string)
+		if !ok {
+			message = http.StatusText(he.Code)
+		}
+		resp := ResponseError(message, nil)
+		resp.Code = statusCode[he.Code]
+		return he.Code, resp
+	}
+
+	// Other errors come from the database, the cache or the network. Their
+	// text may hold SQL and column names, so server errors only report the
+	// status; the error itself is logged.
+	message := err.Error()
+	if fallbackStatus >= http.StatusInternalServerError {
+		message = http.StatusText(fallbackStatus)
+	}
+	resp := ResponseError(message, nil)
+	resp.Code = statusCode[fallbackStatus]
+	return fallbackStatus, resp
+}
+
+// HandleError writes the response of err. Server errors are logged, since
+// their response does not describe them.
+func HandleError(c echo.Context, err error, code int) error {
+	status, resp := ErrorResponse(err, code)
+	if status >= http.StatusInternalServerError {
+		ctx := c.Request().Context()
+		logging.FromContext(ctx).ErrorContext(ctx, "request failed", "error", err)
+	}
+	return c.JSON(status, resp)
+}
+
+// HTTPErrorHandler is the echo error handler for errors returned by handlers
+// and middleware. The request logger records the original error.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, resp := ErrorResponse(err, http.StatusInternalServerError)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, resp)
+	}
+	if err != nil {
+		c.Logger().Error(err)
 	}
-	resp := ResponseError(err.Error(), nil)
-	return c.JSON(code, resp)
 }
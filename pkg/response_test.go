@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/farisarmap/dot-backend-freelance/internal/apperror"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+func TestErrorResponse(t *testing.T) {
+	notFound := apperror.NotFound("user_not_found", "user not found")
+	fieldErr := validator.New().Struct(struct {
+		Name string `validate:"required"`
+	}{})
+
+	testCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "Domain error",
+			err:        notFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "user_not_found",
+		},
+		{
+			name:       "Wrapped domain error",
+			err:        fmt.Errorf("get user: %w", apperror.Wrap(notFound, errors.New("record not found"))),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "user_not_found",
+		},
+		{
+			name:       "Validation error",
+			err:        apperror.Validation("order_user_not_found", "user of the order does not exist"),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "order_user_not_found",
+		},
+		{
+			name:       "Field validation error",
+			err:        fmt.Errorf("line 3: %w", fieldErr),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "validation_failed",
+		},
+		{
+			name:       "Conflict error",
+			err:        apperror.Conflict("email_already_exists", "email is already registered"),
+			wantStatus: http.StatusConflict,
+			wantCode:   "email_already_exists",
+		},
+		{
+			name:       "Precondition required error",
+			err:        apperror.PreconditionRequired("version_required", "version is required"),
+			wantStatus: http.StatusPreconditionRequired,
+			wantCode:   "version_required",
+		},
+		{
+			name:       "Echo HTTP error",
+			err:        echo.NewHTTPError(http.StatusMethodNotAllowed),
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   "method_not_allowed",
+		},
+		{
+			name:       "Unknown error",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, resp := ErrorResponse(tc.err, http.StatusInternalServerError)
+			if status != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, status)
+			}
+			if resp.Code != tc.wantCode {
+				t.Fatalf("expected code %q, got %q", tc.wantCode, resp.Code)
+			}
+			if resp.Status != "error" {
+				t.Fatalf("expected error status, got %q", resp.Status)
+			}
+		})
+	}
+}
+
+func TestErrorResponseHidesServerErrors(t *testing.T) {
+	err := errors.New(`ERROR: duplicate key value violates unique constraint "users_email_key" (SQLSTATE 23505)`)
+
+	_, resp := ErrorResponse(err, http.StatusInternalServerError)
+	if resp.Message != http.StatusText(http.StatusInternalServerError) {
+		t.Fatalf("expected a generic message, got %q", resp.Message)
+	}
+
+	_, resp = ErrorResponse(errors.New("invalid id"), http.StatusBadRequest)
+	if resp.Message != "invalid id" {
+		t.Fatalf("expected client errors to keep their message, got %q", resp.Message)
+	}
+}
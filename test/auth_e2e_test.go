@@ -0,0 +1,186 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func postJSON(t *testing.T, path, body string) (*http.Response, ResponseFormat) {
+	t.Helper()
+
+	resp, err := http.Post(baseURL+path, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("POST %s request error: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	var response ResponseFormat
+	_ = json.Unmarshal(bodyBytes, &response)
+	return resp, response
+}
+
+// authenticate registers a new user and returns an access token for it.
+func authenticate(t *testing.T) string {
+	t.Helper()
+
+	_, token := registerUser(t)
+	return token
+}
+
+// registerUser registers a new user and returns its id and an access token.
+func registerUser(t *testing.T) (uint, string) {
+	t.Helper()
+
+	email := fmt.Sprintf("e2e_auth_%d@testing.com", time.Now().UnixNano())
+	resp, response := postJSON(t, "/auth/register", fmt.Sprintf(`{"name":"E2E Auth","email":%q,"password":"secret-password"}`, email))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register expected %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	var user struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(response.Data, &user); err != nil || user.ID == 0 {
+		t.Fatalf("register returned no user id: %v", err)
+	}
+	return user.ID, login(t, email, "secret-password")
+}
+
+// authenticateAdmin logs in as the admin configured in auth.admin_email,
+// whose credentials are read from E2E_ADMIN_EMAIL and E2E_ADMIN_PASSWORD.
+func authenticateAdmin(t *testing.T) string {
+	t.Helper()
+
+	email, password := os.Getenv("E2E_ADMIN_EMAIL"), os.Getenv("E2E_ADMIN_PASSWORD")
+	if email == "" || password == "" {
+		t.Fatal("E2E_ADMIN_EMAIL and E2E_ADMIN_PASSWORD must be set to the admin configured in auth.admin_email")
+	}
+	return login(t, email, password)
+}
+
+func login(t *testing.T, email, password string) string {
+	t.Helper()
+
+	resp, response := postJSON(t, "/auth/login", fmt.Sprintf(`{"email":%q,"password":%q}`, email, password))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var tokens tokenResponse
+	if err := json.Unmarshal(response.Data, &tokens); err != nil || tokens.AccessToken == "" {
+		t.Fatalf("login returned no access token: %v", err)
+	}
+	return tokens.AccessToken
+}
+
+func TestAuth(t *testing.T) {
+	email := fmt.Sprintf("e2e_login_%d@testing.com", time.Now().UnixNano())
+	var tokens tokenResponse
+
+	t.Run("Register - Success", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/register", fmt.Sprintf(`{"name":"E2E Login","email":%q,"password":"secret-password"}`, email))
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected %d, got %d", http.StatusCreated, resp.StatusCode)
+		}
+	})
+
+	t.Run("Register - Fail (Duplicate email)", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/register", fmt.Sprintf(`{"name":"E2E Login","email":%q,"password":"secret-password"}`, email))
+		if resp.StatusCode != http.StatusConflict {
+			t.Fatalf("expected %d, got %d", http.StatusConflict, resp.StatusCode)
+		}
+	})
+
+	t.Run("Register - Fail (Short password)", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/register", `{"name":"E2E Login","email":"short@testing.com","password":"short"}`)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("expected %d, got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+		}
+	})
+
+	t.Run("Login - Fail (Wrong password)", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/login", fmt.Sprintf(`{"email":%q,"password":"wrong-password"}`, email))
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("Login - Success", func(t *testing.T) {
+		resp, response := postJSON(t, "/auth/login", fmt.Sprintf(`{"email":%q,"password":"secret-password"}`, email))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if err := json.Unmarshal(response.Data, &tokens); err != nil {
+			t.Fatalf("token parse error: %v", err)
+		}
+	})
+
+	t.Run("Protected route - Fail (No token)", func(t *testing.T) {
+		resp, err := http.Get(baseURL + "/users")
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("Protected route - Success", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"/users", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	})
+
+	oldRefresh := tokens.RefreshToken
+
+	t.Run("Refresh - Success", func(t *testing.T) {
+		resp, response := postJSON(t, "/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, oldRefresh))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if err := json.Unmarshal(response.Data, &tokens); err != nil {
+			t.Fatalf("token parse error: %v", err)
+		}
+	})
+
+	t.Run("Refresh - Fail (Reused token)", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, oldRefresh))
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("Logout - Success", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/logout", fmt.Sprintf(`{"refresh_token":%q}`, tokens.RefreshToken))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	})
+
+	t.Run("Refresh - Fail (Revoked token)", func(t *testing.T) {
+		resp, _ := postJSON(t, "/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, tokens.RefreshToken))
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+}
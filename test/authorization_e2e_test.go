@@ -0,0 +1,87 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func doAuthorized(t *testing.T, method, url, token, body string) (*http.Response, ResponseFormat) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("request creation error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	var response ResponseFormat
+	_ = json.Unmarshal(bodyBytes, &response)
+	return resp, response
+}
+
+func TestOrderAuthorization(t *testing.T) {
+	ownerID, owner := registerUser(t)
+	other := authenticate(t)
+	admin := authenticateAdmin(t)
+
+	var orderID uint
+
+	t.Run("Setup - Owner creates order", func(t *testing.T) {
+		resp, response := doAuthorized(t, http.MethodPost, baseURL+"/orders", owner,
+			fmt.Sprintf(`{"order_name":"E2E Owned Order","user_id":%d}`, ownerID))
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected %d, got %d", http.StatusCreated, resp.StatusCode)
+		}
+		var order struct {
+			ID uint `json:"id"`
+		}
+		if err := json.Unmarshal(response.Data, &order); err != nil {
+			t.Fatalf("order parse error: %v", err)
+		}
+		orderID = order.ID
+	})
+
+	orderURL := func() string { return fmt.Sprintf("%s/orders/%d", baseURL, orderID) }
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        func() string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{"Owner can read order", http.MethodGet, orderURL, owner, "", http.StatusOK},
+		{"Other user cannot read order", http.MethodGet, orderURL, other, "", http.StatusForbidden},
+		{"Other user cannot update order", http.MethodPatch, orderURL, other, `{"order_name":"Hijacked"}`, http.StatusForbidden},
+		{"Other user cannot delete order", http.MethodDelete, orderURL, other, "", http.StatusForbidden},
+		{"Admin can read order", http.MethodGet, orderURL, admin, "", http.StatusOK},
+		{"Owner sees order in own list", http.MethodGet, func() string { return baseURL + "/me/orders" }, owner, "", http.StatusOK},
+		{"User cannot create order for others", http.MethodPost, func() string { return baseURL + "/orders" }, other,
+			fmt.Sprintf(`{"order_name":"E2E Foreign Order","user_id":%d}`, ownerID), http.StatusForbidden},
+		{"User cannot list all orders", http.MethodGet, func() string { return baseURL + "/orders" }, other, "", http.StatusForbidden},
+		{"User cannot list users", http.MethodGet, func() string { return baseURL + "/users" }, other, "", http.StatusForbidden},
+		{"Owner can delete order", http.MethodDelete, orderURL, owner, "", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, _ := doAuthorized(t, tc.method, tc.url(), tc.token, tc.body)
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("expected %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 )
 
 func TestOrderCRUD(t *testing.T) {
+	token := authenticateAdmin(t)
 	var (
 		createdUserID  int
 		createdOrderID int
@@ -24,6 +25,7 @@ func TestOrderCRUD(t *testing.T) {
 			t.Fatalf("Setup - CreateUser request creation error: %v", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
 
 		client := &http.Client{}
 		resp, err := client.Do(req)
@@ -65,6 +67,7 @@ func TestOrderCRUD(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Cleanup - DeleteUser request creation error: %v", err)
 			}
+			req.Header.Set("Authorization", "Bearer "+token)
 
 			client := &http.Client{}
 			resp, err := client.Do(req)
@@ -123,7 +126,7 @@ func TestOrderCRUD(t *testing.T) {
 				return baseURL + "/orders"
 			},
 			body:       `{"order_name":"Invalid User Order","user_id":999999}`,
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "CreateOrder - Fail (Missing order_name)",
@@ -132,7 +135,7 @@ func TestOrderCRUD(t *testing.T) {
 				return baseURL + "/orders"
 			},
 			body:       fmt.Sprintf(`{"user_id":%d}`, createdUserID),
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "GetAllOrders - Success",
@@ -156,7 +159,7 @@ func TestOrderCRUD(t *testing.T) {
 			urlFn: func() string {
 				return fmt.Sprintf("%s/orders/%d", baseURL, 999999)
 			},
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:   "UpdateOrder - Success",
@@ -192,7 +195,7 @@ func TestOrderCRUD(t *testing.T) {
 				return fmt.Sprintf("%s/orders/%d", baseURL, createdOrderID)
 			},
 			body:       fmt.Sprintf(`{"order_name":"","user_id":%d}`, createdUserID),
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "UpdateOrder - Fail (Invalid UserID)",
@@ -201,7 +204,7 @@ func TestOrderCRUD(t *testing.T) {
 				return fmt.Sprintf("%s/orders/%d", baseURL, createdOrderID)
 			},
 			body:       `{"order_name":"E2E Updated Order","user_id":999999}`,
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "PartialUpdateOrder - Success (OrderName only)",
@@ -237,7 +240,7 @@ func TestOrderCRUD(t *testing.T) {
 				return fmt.Sprintf("%s/orders/%d", baseURL, createdOrderID)
 			},
 			body:       `{"order_name":""}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "DeleteOrder - Success",
@@ -254,7 +257,7 @@ func TestOrderCRUD(t *testing.T) {
 				// Order ini sudah dihapus
 				return fmt.Sprintf("%s/orders/%d", baseURL, createdOrderID)
 			},
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusNotFound,
 		},
 	}
 
@@ -272,6 +275,7 @@ func TestOrderCRUD(t *testing.T) {
 				t.Fatalf("[%s] request creation error: %v", tc.name, err)
 			}
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
 
 			// Kirim request
 			client := &http.Client{}
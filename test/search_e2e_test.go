@@ -0,0 +1,80 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type searchHit struct {
+	Type    string `json:"type"`
+	ID      uint   `json:"id"`
+	Snippet string `json:"snippet"`
+}
+
+func search(t *testing.T, token, q string) []searchHit {
+	t.Helper()
+
+	resp, response := doAuthorized(t, http.MethodGet, baseURL+"/search?q="+url.QueryEscape(q), token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("search expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var hits []searchHit
+	if err := json.Unmarshal(response.Data, &hits); err != nil {
+		t.Fatalf("search parse error: %v", err)
+	}
+	return hits
+}
+
+func findHit(hits []searchHit, id uint) (searchHit, bool) {
+	for _, hit := range hits {
+		if hit.Type == "order" && hit.ID == id {
+			return hit, true
+		}
+	}
+	return searchHit{}, false
+}
+
+func TestSearch(t *testing.T) {
+	ownerID, owner := registerUser(t)
+	word := fmt.Sprintf("zq%d", time.Now().UnixNano())
+
+	resp, response := doAuthorized(t, http.MethodPost, baseURL+"/orders", owner,
+		fmt.Sprintf(`{"order_name":"<img src=x onerror=alert(1)> %s","user_id":%d}`, word, ownerID))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create order expected %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	var order struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(response.Data, &order); err != nil {
+		t.Fatalf("order parse error: %v", err)
+	}
+
+	t.Run("Matches a prefix and escapes the snippet", func(t *testing.T) {
+		hit, ok := findHit(search(t, owner, word[:10]), order.ID)
+		if !ok {
+			t.Fatalf("expected order %d to match the prefix %q", order.ID, word[:10])
+		}
+		if strings.Contains(hit.Snippet, "<img") || !strings.Contains(hit.Snippet, "&lt;img") {
+			t.Errorf("expected the order name to be escaped, got %q", hit.Snippet)
+		}
+		if !strings.Contains(hit.Snippet, "<mark>"+word+"</mark>") {
+			t.Errorf("expected the match to be marked, got %q", hit.Snippet)
+		}
+	})
+
+	t.Run("Excludes deleted orders", func(t *testing.T) {
+		resp, _ := doAuthorized(t, http.MethodDelete, fmt.Sprintf("%s/orders/%d", baseURL, order.ID), owner, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("delete order expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if _, ok := findHit(search(t, owner, word), order.ID); ok {
+			t.Errorf("expected deleted order %d not to be found", order.ID)
+		}
+	})
+}
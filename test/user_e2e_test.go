@@ -18,6 +18,7 @@ type ResponseFormat struct {
 }
 
 func TestUserCRUD(t *testing.T) {
+	token := authenticateAdmin(t)
 	var createdUserID int
 
 	testCases := []struct {
@@ -64,7 +65,7 @@ func TestUserCRUD(t *testing.T) {
 				return baseURL + "/users"
 			},
 			body:       `{"name":"Invalid Email","email":"not-an-email"}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "CreateUser - Fail (Missing name)",
@@ -73,7 +74,7 @@ func TestUserCRUD(t *testing.T) {
 				return baseURL + "/users"
 			},
 			body:       `{"email":"no-name@testing.com"}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "GetAllUsers - Success",
@@ -107,7 +108,7 @@ func TestUserCRUD(t *testing.T) {
 				return fmt.Sprintf("%s/users/%d", baseURL, createdUserID)
 			},
 			body:       `{"name":"Xyz","email":"not-email"}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "PartialUpdateUser - Success (Name only)",
@@ -125,7 +126,7 @@ func TestUserCRUD(t *testing.T) {
 				return fmt.Sprintf("%s/users/%d", baseURL, createdUserID)
 			},
 			body:       `{"email":"this-is-not-email"}`,
-			wantStatus: http.StatusBadRequest,
+			wantStatus: http.StatusUnprocessableEntity,
 		},
 		{
 			name:   "DeleteUser - Success",
@@ -151,6 +152,7 @@ func TestUserCRUD(t *testing.T) {
 				t.Fatalf("[%s] request creation error: %v", tc.name, err)
 			}
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
 
 			// Kirim request
 			client := &http.Client{}